@@ -0,0 +1,174 @@
+package exercise02workerpool
+
+import (
+	"context" // Package for stopping the autoscaler together with the run.
+	"errors"  // Package for recognising ErrPoolStopped.
+	"fmt"     // Package for formatting scaling decisions.
+	"time"    // Package for the sampling interval and the latency figures.
+)
+
+// AutoscalerConfig holds the bounds and thresholds used by an Autoscaler.
+// Zero values select the defaults documented on each field.
+type AutoscalerConfig struct {
+	MinWorkers int           // Lower bound for the pool size. Defaults to 1.
+	MaxWorkers int           // Upper bound for the pool size. Defaults to MinWorkers.
+	Interval   time.Duration // How often the pool is sampled. Defaults to 500ms.
+	Step       int           // Workers added or removed per decision. Defaults to a quarter of the current size (at least 1).
+
+	HighUtilisation float64 // Utilisation (0..1) at or above which the pool may grow. Defaults to 0.8.
+	LowUtilisation  float64 // Utilisation (0..1) at or below which the pool shrinks. Defaults to 0.3.
+
+	// Producer, if set, reports how long the producer has been blocked on TaskChan.
+	// The pool only grows while the producer spends at least BlockedThreshold of
+	// each interval blocked, i.e. while there is work waiting for a free worker.
+	Producer         BlockReporter
+	BlockedThreshold float64 // Fraction (0..1) of the interval. Defaults to 0.5.
+
+	// TargetLatency, if set, stops the pool from growing while the average time
+	// from taking a task to delivering its result exceeds it. A rising latency
+	// means results are held up (by the CPU or by a slow consumer), so more
+	// workers would not help.
+	TargetLatency time.Duration
+
+	// OnDecision, if set, is called for every resize the autoscaler performs.
+	OnDecision func(ScalingDecision)
+}
+
+// ScalingDecision describes one resize performed by an Autoscaler and the
+// measurements that led to it.
+type ScalingDecision struct {
+	At              time.Time     // When the decision was taken.
+	From            int           // Pool size before the decision.
+	To              int           // Pool size after the decision.
+	Utilisation     float64       // Fraction of worker time spent processing during the interval.
+	ProducerBlocked float64       // Fraction of the interval the producer spent blocked (0 without a Producer).
+	Latency         time.Duration // Average latency of the results delivered during the interval.
+	Reason          string        // Human-readable explanation of the decision.
+}
+
+// String formats the decision as a single log line.
+func (d ScalingDecision) String() string {
+	return fmt.Sprintf("workers %d -> %d (utilisation %.0f%%, producer blocked %.0f%%, latency %v): %s",
+		d.From, d.To, d.Utilisation*100, d.ProducerBlocked*100, d.Latency.Round(time.Millisecond), d.Reason)
+}
+
+// Autoscaler periodically samples a pool and resizes it between the configured
+// bounds, growing while workers are saturated and the producer is waiting for
+// them, and shrinking while workers are mostly idle.
+type Autoscaler[In, Out any] struct {
+	pool *TypedPool[In, Out] // The pool being scaled.
+	cfg  AutoscalerConfig    // The configuration, with defaults applied.
+}
+
+// NewAutoscaler creates an Autoscaler for pool. Call Run after pool.Start.
+func NewAutoscaler[In, Out any](pool *TypedPool[In, Out], cfg AutoscalerConfig) *Autoscaler[In, Out] {
+	if cfg.MinWorkers < 1 {
+		cfg.MinWorkers = 1
+	}
+	if cfg.MaxWorkers < cfg.MinWorkers {
+		cfg.MaxWorkers = cfg.MinWorkers
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = 500 * time.Millisecond
+	}
+	if cfg.HighUtilisation <= 0 {
+		cfg.HighUtilisation = 0.8
+	}
+	if cfg.LowUtilisation <= 0 {
+		cfg.LowUtilisation = 0.3
+	}
+	if cfg.BlockedThreshold <= 0 {
+		cfg.BlockedThreshold = 0.5
+	}
+	return &Autoscaler[In, Out]{pool: pool, cfg: cfg}
+}
+
+// Run samples the pool every Interval and resizes it until ctx is cancelled or
+// the pool's run is over. It is designed to be run in its own goroutine.
+func (a *Autoscaler[In, Out]) Run(ctx context.Context) {
+	// Bring the pool within bounds before the first sample.
+	if size := a.pool.Size(); size < a.cfg.MinWorkers || size > a.cfg.MaxWorkers {
+		target := min(max(size, a.cfg.MinWorkers), a.cfg.MaxWorkers)
+		if !a.resize(ScalingDecision{At: time.Now(), From: size, To: target, Reason: "pool size outside the configured bounds"}) {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(a.cfg.Interval)
+	defer ticker.Stop()
+
+	lastAt := time.Now()
+	last := a.pool.load()
+	var lastBlocked time.Duration
+	if a.cfg.Producer != nil {
+		lastBlocked = a.cfg.Producer.BlockedTime()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.pool.done:
+			return
+		case now := <-ticker.C:
+			size := a.pool.Size()
+			current := a.pool.load()
+			elapsed := now.Sub(lastAt)
+
+			d := ScalingDecision{At: now, From: size, To: size}
+			// Both fractions are clamped: busy and blocked time are only added when a
+			// task or a send completes, so a long one can spill over into the next interval.
+			if size > 0 && elapsed > 0 {
+				d.Utilisation = min(float64(current.busy-last.busy)/(float64(elapsed)*float64(size)), 1)
+			}
+			if n := current.delivered - last.delivered; n > 0 {
+				d.Latency = (current.latency - last.latency) / time.Duration(n)
+			}
+			starved := true // Without a producer to ask, utilisation alone decides.
+			if a.cfg.Producer != nil {
+				blocked := a.cfg.Producer.BlockedTime()
+				d.ProducerBlocked = min(float64(blocked-lastBlocked)/float64(elapsed), 1)
+				starved = d.ProducerBlocked >= a.cfg.BlockedThreshold
+				lastBlocked = blocked
+			}
+			lastAt, last = now, current
+
+			a.decide(&d, starved)
+			if d.To != d.From && !a.resize(d) {
+				return
+			}
+		}
+	}
+}
+
+// decide fills in d.To and d.Reason from the measurements already stored in d.
+func (a *Autoscaler[In, Out]) decide(d *ScalingDecision, starved bool) {
+	step := a.cfg.Step
+	if step <= 0 {
+		step = max(d.From/4, 1)
+	}
+
+	switch {
+	case d.Utilisation >= a.cfg.HighUtilisation && starved && d.From < a.cfg.MaxWorkers:
+		if a.cfg.TargetLatency > 0 && d.Latency > a.cfg.TargetLatency {
+			return // Saturated, but adding workers would not bring results out faster.
+		}
+		d.To = min(d.From+step, a.cfg.MaxWorkers)
+		d.Reason = "workers saturated and tasks waiting"
+	case d.Utilisation <= a.cfg.LowUtilisation && d.From > a.cfg.MinWorkers:
+		d.To = max(d.From-step, a.cfg.MinWorkers)
+		d.Reason = "workers mostly idle"
+	}
+}
+
+// resize applies d to the pool and reports it. It returns false once the pool
+// is shutting down, which ends Run.
+func (a *Autoscaler[In, Out]) resize(d ScalingDecision) bool {
+	if err := a.pool.Resize(d.To); err != nil {
+		return !errors.Is(err, ErrPoolStopped)
+	}
+	if a.cfg.OnDecision != nil {
+		a.cfg.OnDecision(d)
+	}
+	return true
+}
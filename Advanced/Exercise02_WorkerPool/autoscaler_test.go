@@ -0,0 +1,62 @@
+package exercise02workerpool_test
+
+import (
+	"context" // Used to start the pool, producer and autoscaler.
+	"sync"    // Used to guard the recorded decisions.
+	"testing" // The testing package is required for tests.
+	"time"    // Used for the sampling interval and task complexity.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// TestAutoscalerGrowsSaturatedPool feeds a single-worker pool with sleep-heavy
+// tasks and checks that the autoscaler grows it, staying within MaxWorkers.
+func TestAutoscalerGrowsSaturatedPool(t *testing.T) {
+	ctx := context.Background()
+	sleepy := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		time.Sleep(task.Complexity)
+		return nil, nil
+	})
+
+	pool := exercise02workerpool.NewPool(1, sleepy)
+	pool.Start(ctx)
+
+	producer := exercise02workerpool.NewTypedProducer(300, pool.TaskChan, func(id int) exercise02workerpool.Task {
+		return exercise02workerpool.Task{ID: id, Complexity: 10 * time.Millisecond}
+	})
+	go producer.Start(ctx)
+
+	var mu sync.Mutex
+	var decisions []exercise02workerpool.ScalingDecision
+	autoscaler := exercise02workerpool.NewAutoscaler(pool, exercise02workerpool.AutoscalerConfig{
+		MinWorkers: 1,
+		MaxWorkers: 6,
+		Interval:   20 * time.Millisecond,
+		Producer:   producer,
+		OnDecision: func(d exercise02workerpool.ScalingDecision) {
+			mu.Lock()
+			defer mu.Unlock()
+			decisions = append(decisions, d)
+		},
+	})
+	go autoscaler.Run(ctx)
+
+	if results := collect(t, pool.ResultChan); len(results) != 300 {
+		t.Fatalf("got %d results, want 300", len(results))
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	grew := false
+	for _, d := range decisions {
+		if d.To > 6 || d.To < 1 {
+			t.Errorf("decision out of bounds: %v", d)
+		}
+		if d.To > d.From {
+			grew = true
+		}
+	}
+	if !grew {
+		t.Errorf("expected the pool to grow; decisions: %v", decisions)
+	}
+}
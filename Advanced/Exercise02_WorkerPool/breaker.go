@@ -0,0 +1,193 @@
+package exercise02workerpool
+
+import (
+	"context"     // Package for recognising cancellation, which is not a backend failure.
+	"errors"      // Package for defining ErrCircuitOpen.
+	"sync"        // Package for the mutex guarding the breaker's state.
+	"sync/atomic" // Package for the counter of rejected attempts.
+	"time"        // Package for the cool-down period.
+)
+
+// ErrCircuitOpen is set in Task.Err when an attempt was rejected without being
+// processed because the pool's circuit breaker is open. Test for it with errors.Is.
+var ErrCircuitOpen = errors.New("workerpool: circuit breaker is open")
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	CircuitClosed   CircuitState = iota // Attempts are processed normally.
+	CircuitOpen                         // Attempts fail fast with ErrCircuitOpen until the cool-down elapses.
+	CircuitHalfOpen                     // A few trial attempts are processed to find out whether to close again.
+)
+
+// String returns the state's name.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// CircuitBreakerConfig holds the thresholds of a CircuitBreaker.
+// Zero values select the defaults documented on each field.
+type CircuitBreakerConfig struct {
+	FailureThreshold int              // Consecutive failures that open the circuit. Defaults to 5.
+	CoolDown         time.Duration    // How long the circuit stays open before trial attempts. Defaults to 5s.
+	HalfOpenProbes   int              // Trial attempts let through while half-open; all must succeed to close. Defaults to 1.
+	IsFailure        func(error) bool // Decides whether an error counts as a failure. Defaults to any error except context.Canceled.
+
+	// OnStateChange, if set, is called after every change of state. It must not block.
+	OnStateChange func(from, to CircuitState)
+}
+
+// CircuitBreaker stops a pool from calling a failing backend. While closed it
+// counts consecutive failures; after FailureThreshold of them it opens and every
+// attempt fails fast with ErrCircuitOpen. Once CoolDown has elapsed it turns
+// half-open and lets HalfOpenProbes attempts through: if they all succeed it
+// closes, and a single failure opens it again. It is safe for concurrent use,
+// and a nil *CircuitBreaker lets every attempt through.
+type CircuitBreaker struct {
+	cfg CircuitBreakerConfig // The configuration, with defaults applied.
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int       // Consecutive failures while closed.
+	openedAt  time.Time // When the circuit last opened.
+	probes    int       // Trial attempts let through since turning half-open.
+	successes int       // Trial attempts that succeeded since turning half-open.
+
+	rejected atomic.Int64 // Attempts rejected while open.
+}
+
+// NewCircuitBreaker returns a closed circuit breaker configured by cfg.
+func NewCircuitBreaker(cfg CircuitBreakerConfig) *CircuitBreaker {
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.CoolDown <= 0 {
+		cfg.CoolDown = 5 * time.Second
+	}
+	if cfg.HalfOpenProbes <= 0 {
+		cfg.HalfOpenProbes = 1
+	}
+	if cfg.IsFailure == nil {
+		cfg.IsFailure = func(err error) bool { return err != nil && !errors.Is(err, context.Canceled) }
+	}
+	return &CircuitBreaker{cfg: cfg}
+}
+
+// State returns the current state of the breaker.
+func (b *CircuitBreaker) State() CircuitState {
+	if b == nil {
+		return CircuitClosed
+	}
+	b.mu.Lock()
+	from := b.state
+	b.coolDown(time.Now())
+	state := b.state
+	b.mu.Unlock()
+
+	b.notify(from, state)
+	return state
+}
+
+// Rejected returns the number of attempts rejected with ErrCircuitOpen so far.
+func (b *CircuitBreaker) Rejected() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.rejected.Load()
+}
+
+// Allow reports whether an attempt may be processed now. Every allowed attempt
+// must be followed by a call to Record with its outcome.
+func (b *CircuitBreaker) Allow() bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	from := b.state
+	b.coolDown(time.Now())
+	allowed := true
+	switch b.state {
+	case CircuitOpen:
+		allowed = false
+	case CircuitHalfOpen:
+		if b.probes >= b.cfg.HalfOpenProbes {
+			allowed = false
+		} else {
+			b.probes++
+		}
+	}
+	state := b.state
+	b.mu.Unlock()
+
+	if !allowed {
+		b.rejected.Add(1)
+	}
+	b.notify(from, state)
+	return allowed
+}
+
+// Record reports the outcome of an attempt that Allow let through.
+func (b *CircuitBreaker) Record(err error) {
+	if b == nil {
+		return
+	}
+	failed := b.cfg.IsFailure(err)
+
+	b.mu.Lock()
+	from := b.state
+	switch b.state {
+	case CircuitClosed:
+		if !failed {
+			b.failures = 0
+		} else if b.failures++; b.failures >= b.cfg.FailureThreshold {
+			b.open(time.Now())
+		}
+	case CircuitHalfOpen:
+		if failed {
+			b.open(time.Now())
+		} else if b.successes++; b.successes >= b.cfg.HalfOpenProbes {
+			b.state = CircuitClosed
+			b.failures = 0
+		}
+	case CircuitOpen:
+		// An attempt let through before the circuit opened; it changes nothing.
+	}
+	state := b.state
+	b.mu.Unlock()
+
+	b.notify(from, state)
+}
+
+// open moves the breaker to CircuitOpen. The caller must hold b.mu.
+func (b *CircuitBreaker) open(now time.Time) {
+	b.state = CircuitOpen
+	b.openedAt = now
+}
+
+// coolDown turns an open breaker half-open once its cool-down has elapsed.
+// The caller must hold b.mu.
+func (b *CircuitBreaker) coolDown(now time.Time) {
+	if b.state == CircuitOpen && now.Sub(b.openedAt) >= b.cfg.CoolDown {
+		b.state = CircuitHalfOpen
+		b.probes = 0
+		b.successes = 0
+	}
+}
+
+// notify calls OnStateChange if the state changed. It is called without b.mu
+// held, so the callback may use the breaker.
+func (b *CircuitBreaker) notify(from, to CircuitState) {
+	if from != to && b.cfg.OnStateChange != nil {
+		b.cfg.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,103 @@
+package exercise02workerpool_test
+
+import (
+	"context"     // Used to start the pool.
+	"errors"      // Used to make tasks fail and to check ErrCircuitOpen.
+	"slices"      // Used to compare the state changes.
+	"sync/atomic" // Used to count the calls reaching the processor.
+	"testing"     // The testing package is required for tests.
+	"time"        // Used for the cool-down.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// TestCircuitBreakerStates walks a breaker through closed, open, half-open and back.
+func TestCircuitBreakerStates(t *testing.T) {
+	var changes []string
+	breaker := exercise02workerpool.NewCircuitBreaker(exercise02workerpool.CircuitBreakerConfig{
+		FailureThreshold: 2,
+		CoolDown:         20 * time.Millisecond,
+		OnStateChange: func(from, to exercise02workerpool.CircuitState) {
+			changes = append(changes, from.String()+"->"+to.String())
+		},
+	})
+	errBackend := errors.New("backend down")
+
+	// A success resets the count of consecutive failures.
+	for _, err := range []error{errBackend, nil, errBackend} {
+		if !breaker.Allow() {
+			t.Fatal("a closed breaker rejected an attempt")
+		}
+		breaker.Record(err)
+	}
+	if s := breaker.State(); s != exercise02workerpool.CircuitClosed {
+		t.Fatalf("state %v after non-consecutive failures, want closed", s)
+	}
+
+	breaker.Allow()
+	breaker.Record(errBackend)
+	if breaker.Allow() {
+		t.Fatal("an open breaker let an attempt through")
+	}
+
+	// After the cool-down one probe is let through; its failure reopens the circuit.
+	time.Sleep(25 * time.Millisecond)
+	if !breaker.Allow() {
+		t.Fatal("the half-open breaker rejected its probe")
+	}
+	if breaker.Allow() {
+		t.Fatal("the half-open breaker let a second probe through")
+	}
+	breaker.Record(errBackend)
+
+	// A successful probe closes it.
+	time.Sleep(25 * time.Millisecond)
+	if !breaker.Allow() {
+		t.Fatal("the half-open breaker rejected its probe")
+	}
+	breaker.Record(nil)
+
+	want := []string{"closed->open", "open->half-open", "half-open->open", "open->half-open", "half-open->closed"}
+	if !slices.Equal(changes, want) {
+		t.Errorf("got state changes %v, want %v", changes, want)
+	}
+	if n := breaker.Rejected(); n != 2 {
+		t.Errorf("got %d rejected attempts, want 2", n)
+	}
+}
+
+// TestPoolCircuitBreaker checks that an open breaker fails tasks fast without
+// calling the processor, and that Stats reports it.
+func TestPoolCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	proc := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		calls.Add(1)
+		return nil, errors.New("backend down")
+	})
+	breaker := exercise02workerpool.NewCircuitBreaker(exercise02workerpool.CircuitBreakerConfig{
+		FailureThreshold: 3,
+		CoolDown:         time.Hour,
+	})
+	pool := exercise02workerpool.NewPool(1, proc, exercise02workerpool.WithCircuitBreaker(breaker))
+	pool.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 20; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i}
+		}
+		close(pool.TaskChan)
+	}()
+
+	rejected := 0
+	for _, task := range collect(t, pool.ResultChan) {
+		if errors.Is(task.Err, exercise02workerpool.ErrCircuitOpen) {
+			rejected++
+		}
+	}
+	if calls.Load() != 3 || rejected != 17 {
+		t.Errorf("got %d processor calls and %d rejections, want 3 and 17", calls.Load(), rejected)
+	}
+	if s := pool.Stats(); s.Circuit != exercise02workerpool.CircuitOpen || s.CircuitRejected != 17 {
+		t.Errorf("Stats reports circuit %v with %d rejections, want open with 17", s.Circuit, s.CircuitRejected)
+	}
+}
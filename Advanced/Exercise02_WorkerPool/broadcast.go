@@ -0,0 +1,203 @@
+package exercise02workerpool
+
+import (
+	"context"     // Package for cancellation signals that stop the broadcast.
+	"log/slog"    // Package for the structured events logged about slow subscribers.
+	"slices"      // Package for copying and filtering the list of subscriptions.
+	"sync"        // Package for the mutex guarding the subscriptions.
+	"sync/atomic" // Package for the per-subscription counters read while the broadcast runs.
+)
+
+// SlowPolicy decides what a Broadcaster does with a result when a subscriber's
+// buffer is full.
+type SlowPolicy int
+
+const (
+	SlowBlock      SlowPolicy = iota // Wait until the subscriber has room: the slowest blocking subscriber sets the pace of all.
+	SlowDrop                         // Skip the result for this subscriber only, and count it as dropped.
+	SlowDisconnect                   // Close the subscriber's channel and stop sending to it.
+)
+
+// String returns the policy's name.
+func (p SlowPolicy) String() string {
+	switch p {
+	case SlowBlock:
+		return "block"
+	case SlowDrop:
+		return "drop"
+	case SlowDisconnect:
+		return "disconnect"
+	default:
+		return "unknown"
+	}
+}
+
+// TypedBroadcaster reads every result from a result channel, such as
+// Pool.ResultChan, and sends it to each of its subscribers, so several
+// independent consumers (e.g. a file writer and a live display) all receive
+// every result. Each subscription has its own buffer and SlowPolicy. The
+// subscribers share the tasks they receive, so they must not modify the
+// Errors slice of a task.
+type TypedBroadcaster[In, Out any] struct {
+	Logger *slog.Logger // Receives an event when a subscriber is disconnected. Nil logs nothing.
+
+	source <-chan TypedTask[In, Out]
+	mu     sync.Mutex
+	subs   []*TypedSubscription[In, Out]
+	nextID int
+	done   bool // Whether the broadcast has ended and every channel was closed.
+}
+
+// Broadcaster is a TypedBroadcaster for the prime-checking Task.
+type Broadcaster = TypedBroadcaster[int, any]
+
+// TypedSubscription is a subscriber's view of a TypedBroadcaster: its own
+// channel of results, which the broadcaster closes once the results end, the
+// subscriber is disconnected or it unsubscribes.
+type TypedSubscription[In, Out any] struct {
+	ResultChan <-chan TypedTask[In, Out] // Every result, in the order the broadcaster received them.
+
+	id           int
+	policy       SlowPolicy
+	ch           chan TypedTask[In, Out]
+	quit         chan struct{} // Closed by Unsubscribe.
+	quitOnce     sync.Once
+	dropped      atomic.Int64
+	disconnected atomic.Bool
+}
+
+// Subscription is a TypedSubscription for the prime-checking Task.
+type Subscription = TypedSubscription[int, any]
+
+// NewTypedBroadcaster creates a TypedBroadcaster for the results received from source.
+func NewTypedBroadcaster[In, Out any](source <-chan TypedTask[In, Out]) *TypedBroadcaster[In, Out] {
+	return &TypedBroadcaster[In, Out]{source: source}
+}
+
+// NewBroadcaster creates a Broadcaster for the results received from source.
+func NewBroadcaster(source <-chan Task) *Broadcaster {
+	return NewTypedBroadcaster(source)
+}
+
+// Subscribe adds a subscriber with a buffer of the given size and the policy
+// applied when that buffer is full. Subscribers added while the broadcast runs
+// receive the results from then on; a subscription made after it ended has its
+// channel closed already.
+func (b *TypedBroadcaster[In, Out]) Subscribe(buffer int, policy SlowPolicy) *TypedSubscription[In, Out] {
+	ch := make(chan TypedTask[In, Out], max(buffer, 0))
+	s := &TypedSubscription[In, Out]{ResultChan: ch, policy: policy, ch: ch, quit: make(chan struct{})}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done {
+		close(ch)
+		return s
+	}
+	s.id = b.nextID
+	b.nextID++
+	b.subs = append(b.subs, s)
+	return s
+}
+
+// Start begins the broadcast. This method is designed to be run in its own
+// goroutine. It returns once the source channel is closed and drained, or when
+// ctx is cancelled, and closes every subscriber's channel before returning.
+// Results received while there are no subscribers are discarded, so the
+// source is always drained.
+func (b *TypedBroadcaster[In, Out]) Start(ctx context.Context) {
+	defer b.closeAll()
+	for {
+		select {
+		case task, ok := <-b.source:
+			if !ok {
+				return
+			}
+			if !b.publish(ctx, task) {
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// publish sends task to every subscriber according to its policy. It returns
+// false if ctx was cancelled while waiting for a blocking subscriber.
+func (b *TypedBroadcaster[In, Out]) publish(ctx context.Context, task TypedTask[In, Out]) bool {
+	b.mu.Lock()
+	subs := slices.Clone(b.subs)
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		select {
+		case <-s.quit:
+			b.remove(s)
+			continue
+		default:
+		}
+
+		// Try without waiting first: with room in the buffer, every policy agrees.
+		select {
+		case s.ch <- task:
+			continue
+		default:
+		}
+
+		switch s.policy {
+		case SlowDrop:
+			s.dropped.Add(1)
+		case SlowDisconnect:
+			s.disconnected.Store(true)
+			loggerOrDiscard(b.Logger).WarnContext(ctx, "slow subscriber disconnected", "subscriber", s.id, "task", task.ID)
+			b.remove(s)
+		default: // SlowBlock
+			select {
+			case s.ch <- task:
+			case <-s.quit:
+				b.remove(s)
+			case <-ctx.Done():
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// remove stops sending to s and closes its channel. Only the broadcast
+// goroutine sends on the channels, so only it closes them.
+func (b *TypedBroadcaster[In, Out]) remove(s *TypedSubscription[In, Out]) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs = slices.DeleteFunc(b.subs, func(other *TypedSubscription[In, Out]) bool { return other == s })
+	close(s.ch)
+}
+
+// closeAll ends the broadcast and closes the channel of every subscriber left.
+func (b *TypedBroadcaster[In, Out]) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.done = true
+	for _, s := range b.subs {
+		close(s.ch)
+	}
+	b.subs = nil
+}
+
+// Unsubscribe stops the results to this subscriber. Its channel is closed by the
+// broadcaster, at the latest when the next result arrives; results already
+// buffered can still be received. It is safe to call more than once.
+func (s *TypedSubscription[In, Out]) Unsubscribe() {
+	s.quitOnce.Do(func() { close(s.quit) })
+}
+
+// Dropped returns the number of results skipped because the buffer was full,
+// with the SlowDrop policy.
+func (s *TypedSubscription[In, Out]) Dropped() int {
+	return int(s.dropped.Load())
+}
+
+// Disconnected reports whether the broadcaster closed the channel because the
+// buffer was full, with the SlowDisconnect policy.
+func (s *TypedSubscription[In, Out]) Disconnected() bool {
+	return s.disconnected.Load()
+}
@@ -0,0 +1,108 @@
+package exercise02workerpool_test
+
+import (
+	"context" // Used to run the pool and the broadcaster.
+	"sync"    // Used to wait for the subscribers.
+	"testing" // The testing package is required for tests.
+	"time"    // Used for test timeouts.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// TestBroadcasterFanOut checks that every blocking subscriber receives every
+// result of a pool, however slow it is.
+func TestBroadcasterFanOut(t *testing.T) {
+	pool := exercise02workerpool.NewPool(4, nil)
+	broadcaster := exercise02workerpool.NewBroadcaster(pool.ResultChan)
+	fast := broadcaster.Subscribe(0, exercise02workerpool.SlowBlock)
+	slow := broadcaster.Subscribe(1, exercise02workerpool.SlowBlock)
+	pool.Start(context.Background())
+	go broadcaster.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 20; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i, Data: i}
+		}
+		close(pool.TaskChan)
+	}()
+
+	var wg sync.WaitGroup
+	counts := make([]int, 2)
+	for i, sub := range []*exercise02workerpool.Subscription{fast, slow} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range sub.ResultChan {
+				if sub == slow {
+					time.Sleep(time.Millisecond)
+				}
+				counts[i]++
+			}
+		}()
+	}
+	wg.Wait()
+	if counts[0] != 20 || counts[1] != 20 {
+		t.Errorf("subscribers received %v results, want 20 each", counts)
+	}
+}
+
+// TestBroadcasterSlowPolicies checks that a subscriber that never reads holds
+// up neither the others nor the source with the drop and disconnect policies.
+func TestBroadcasterSlowPolicies(t *testing.T) {
+	source := make(chan exercise02workerpool.Task)
+	broadcaster := exercise02workerpool.NewBroadcaster(source)
+	reader := broadcaster.Subscribe(0, exercise02workerpool.SlowBlock)
+	dropper := broadcaster.Subscribe(2, exercise02workerpool.SlowDrop)
+	disconnected := broadcaster.Subscribe(2, exercise02workerpool.SlowDisconnect)
+	leaver := broadcaster.Subscribe(0, exercise02workerpool.SlowBlock)
+	leaver.Unsubscribe() // Would block the broadcast if it were still sent results.
+	go broadcaster.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			source <- exercise02workerpool.Task{ID: i}
+		}
+		close(source)
+	}()
+	received := 0
+	timeout := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case _, ok := <-reader.ResultChan:
+			if !ok {
+				done = true
+				break
+			}
+			received++
+		case <-timeout:
+			t.Fatalf("the broadcast was held up after %d results", received)
+		}
+	}
+	if received != 10 {
+		t.Errorf("the reading subscriber received %d results, want 10", received)
+	}
+
+	// The dropping subscriber kept the first two results and dropped the rest.
+	kept := 0
+	for task := range dropper.ResultChan {
+		if task.ID != kept {
+			t.Errorf("the dropping subscriber kept task %d, want %d", task.ID, kept)
+		}
+		kept++
+	}
+	if kept != 2 || dropper.Dropped() != 8 {
+		t.Errorf("the dropping subscriber kept %d and dropped %d results, want 2 and 8", kept, dropper.Dropped())
+	}
+
+	// The disconnected subscriber can still read what was buffered.
+	buffered := 0
+	for range disconnected.ResultChan {
+		buffered++
+	}
+	if !disconnected.Disconnected() || buffered != 2 {
+		t.Errorf("Disconnected is %v with %d buffered results, want true and 2", disconnected.Disconnected(), buffered)
+	}
+	if _, ok := <-leaver.ResultChan; ok {
+		t.Error("the unsubscribed subscriber received a result")
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"errors"  // Package for collecting every validation error at once.
+	"flag"    // Package for parsing command-line flags.
+	"fmt"     // Package for formatting validation errors and the usage text.
+	"math"    // Package for the largest range of task data that can be drawn.
+	"os"      // Package for the program name and the exit status.
+	"runtime" // Package for the default number of workers.
+	"time"    // Package for the duration flags.
+)
+
+// config holds the settings of a run, taken from the command-line flags.
+type config struct {
+	// Workload.
+	input         string        // File to read the tasks from, "-" for stdin; empty generates them.
+	inputFormat   string        // Format of the input: csv or jsonl; empty guesses it from the file name.
+	tasks         int           // Number of tasks to generate.
+	workers       int           // Initial number of workers.
+	minData       int           // Smallest number to check for primality.
+	maxData       int           // Largest number to check for primality.
+	minComplexity time.Duration // Shortest simulated processing time.
+	maxComplexity time.Duration // Longest simulated processing time.
+	seed          int64         // Seed of the producer's random numbers; 0 picks one from the clock.
+	resultBuffer  int           // Capacity of the pool's ResultChan.
+	queue         int           // Capacity of the pool's TaskChan.
+	output        string        // How results are printed: text, json, csv, table or none.
+	outputFile    string        // File the results are written to; empty prints them on stdout.
+	progress      time.Duration // How often progress is reported; zero disables it.
+
+	// Pool behaviour.
+	autoscale        bool
+	minWorkers       int
+	maxWorkers       int
+	taskTimeout      time.Duration
+	maxAttempts      int
+	deadLetters      bool
+	submitRate       float64
+	processRate      float64
+	rateBurst        int
+	breakerThreshold int
+	breakerCoolDown  time.Duration
+	ordered          int
+
+	// Persistence and observability.
+	walPath     string
+	walSync     time.Duration
+	traceFile   string
+	metricsAddr string
+	logLevel    string
+	logFormat   string
+}
+
+// parseConfig parses the command-line flags into a config. Invalid settings are
+// reported together with the usage text, and the program exits with status 2.
+func parseConfig() config {
+	var c config
+
+	// Workload. The defaults reproduce the original run: 1000 tasks checking
+	// numbers up to a million, with one worker per CPU core.
+	flag.StringVar(&c.input, "input", "", "read the tasks from this CSV or JSON Lines file (- for stdin) instead of generating them")
+	flag.StringVar(&c.inputFormat, "input-format", "", "format of -input: csv or jsonl (default from the file extension, jsonl for stdin)")
+	flag.IntVar(&c.tasks, "tasks", 1000, "number of tasks to generate")
+	flag.IntVar(&c.workers, "workers", runtime.NumCPU(), "number of workers (the initial number with -autoscale)")
+	flag.IntVar(&c.minData, "min-data", 1, "smallest number to check for primality")
+	flag.IntVar(&c.maxData, "max-data", 1000000, "largest number to check for primality")
+	flag.DurationVar(&c.minComplexity, "min-complexity", 5*time.Millisecond, "shortest simulated processing time of a task")
+	flag.DurationVar(&c.maxComplexity, "max-complexity", 199*time.Millisecond, "longest simulated processing time of a task")
+	flag.Int64Var(&c.seed, "seed", 0, "seed for the generated tasks, to repeat a run exactly (0 picks a random seed)")
+	flag.IntVar(&c.resultBuffer, "result-buffer", -1, "capacity of the result channel; -1 makes it twice the number of workers")
+	flag.IntVar(&c.queue, "queue", 0, "capacity of the task queue in front of the workers (0 makes every task wait for the pool)")
+	flag.StringVar(&c.output, "output", "text", "how results are printed: text, json (one object per line), csv, table (aligned columns) or none")
+	flag.StringVar(&c.outputFile, "output-file", "", "write the results to this file instead of stdout (with -output json, csv or table)")
+	flag.DurationVar(&c.progress, "progress", 0, "report how many results arrived at this interval, alongside the output (0 disables)")
+
+	// The autoscaler is optional: without -autoscale the pool keeps -workers workers.
+	flag.BoolVar(&c.autoscale, "autoscale", false, "grow and shrink the pool based on utilisation, producer blocking and latency")
+	flag.IntVar(&c.minWorkers, "min-workers", 1, "lower bound for the pool size when -autoscale is set")
+	flag.IntVar(&c.maxWorkers, "max-workers", runtime.NumCPU()*16, "upper bound for the pool size when -autoscale is set")
+	// A default deadline for every task; tasks that run longer come back with ErrTaskTimeout.
+	flag.DurationVar(&c.taskTimeout, "task-timeout", 0, "abandon tasks that take longer than this (0 disables)")
+	// Failed tasks (e.g. timed out ones) can be retried with exponential backoff.
+	flag.IntVar(&c.maxAttempts, "max-attempts", 1, "attempts per task, including the first; above 1 enables retries")
+	// Permanently failed tasks can be routed to a separate dead-letter channel.
+	flag.BoolVar(&c.deadLetters, "dead-letters", false, "report permanently failed tasks separately from the results")
+	// Token-bucket rate limits, e.g. to protect a backend called while processing.
+	flag.Float64Var(&c.submitRate, "submit-rate", 0, "maximum tasks per second sent by the producer (0 disables)")
+	flag.Float64Var(&c.processRate, "process-rate", 0, "maximum task attempts per second started by the pool (0 disables)")
+	flag.IntVar(&c.rateBurst, "rate-burst", 1, "burst size allowed by -submit-rate and -process-rate")
+	// A circuit breaker fails tasks fast while the processing step keeps failing.
+	flag.IntVar(&c.breakerThreshold, "breaker-threshold", 0, "consecutive failures that open the circuit breaker (0 disables it)")
+	flag.DurationVar(&c.breakerCoolDown, "breaker-cooldown", 5*time.Second, "how long the circuit breaker stays open before trying again")
+	// Results can be printed in task ID order instead of completion order.
+	flag.IntVar(&c.ordered, "ordered", 0, "print results in task ID order, holding back at most this many tasks (0 disables)")
+
+	// A write-ahead log lets an interrupted run be resumed where it stopped.
+	flag.StringVar(&c.walPath, "wal", "", "record tasks in this write-ahead log and resume the unfinished ones on restart (empty disables)")
+	flag.DurationVar(&c.walSync, "wal-sync", 0, "how often the write-ahead log is fsynced: 0 after every record, negative never")
+	// Per-task spans can be written to a file and inspected after the run.
+	flag.StringVar(&c.traceFile, "trace-file", "", "write the spans of every task to this file as JSON lines (empty disables)")
+	// The pool's statistics can be scraped by Prometheus while the command runs.
+	flag.StringVar(&c.metricsAddr, "metrics-addr", "", "serve Prometheus metrics at http://ADDR/metrics (e.g. :9090; empty disables)")
+	// Structured logs go to stderr, so they never mix with the results printed on stdout.
+	flag.StringVar(&c.logLevel, "log-level", "info", "minimum level of the logs written to stderr: debug, info, warn or error")
+	flag.StringVar(&c.logFormat, "log-format", "text", "format of the logs written to stderr: text or json")
+
+	flag.Usage = usage
+	flag.Parse()
+
+	if err := c.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr)
+		flag.Usage()
+		os.Exit(2)
+	}
+	return c
+}
+
+// usage prints what the command does and documents every flag.
+// It is shown for -help and -h, and after an invalid setting.
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: %s [flags]\n\n", os.Args[0])
+	fmt.Fprintln(out, "Generates random prime-checking tasks, or reads them with -input, processes")
+	fmt.Fprintln(out, "them with a worker pool and prints every result followed by a summary of the")
+	fmt.Fprintln(out, "run. Press Ctrl+C to stop early; the pool still shuts down cleanly.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "A CSV input starts with a header naming its columns: data (required), id,")
+	fmt.Fprintln(out, "complexity and timeout. A JSON Lines input holds one object per line with")
+	fmt.Fprintln(out, `the same fields, e.g. {"id": 1, "data": 7919, "complexity": "50ms"}.`)
+	fmt.Fprintln(out, "Malformed lines are reported with their line number and skipped.")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Flags:")
+	flag.PrintDefaults()
+}
+
+// validate reports every setting that is out of range or inconsistent.
+func (c config) validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.inputFormat == "" || c.inputFormat == "csv" || c.inputFormat == "jsonl", "-input-format must be csv or jsonl, got %q", c.inputFormat)
+	check(c.input == "" || c.walPath == "", "-input cannot be combined with -wal, which resumes generated tasks")
+	check(c.tasks >= 0, "-tasks must not be negative, got %d", c.tasks)
+	check(c.workers >= 1, "-workers must be at least 1, got %d", c.workers)
+	check(c.minData <= c.maxData, "-min-data (%d) must not exceed -max-data (%d)", c.minData, c.maxData)
+	// The width is computed without overflow as a uint; the producer draws one
+	// of width+1 numbers, which must fit in an int.
+	check(c.minData > c.maxData || uint(c.maxData)-uint(c.minData) < math.MaxInt,
+		"-min-data (%d) to -max-data (%d) spans more numbers than an int can count", c.minData, c.maxData)
+	check(c.minComplexity >= 0, "-min-complexity must not be negative, got %v", c.minComplexity)
+	check(c.minComplexity <= c.maxComplexity, "-min-complexity (%v) must not exceed -max-complexity (%v)", c.minComplexity, c.maxComplexity)
+	check(c.progress >= 0, "-progress must not be negative, got %v", c.progress)
+	check(c.resultBuffer >= -1, "-result-buffer must not be negative, got %d", c.resultBuffer)
+	check(c.queue >= 0, "-queue must not be negative, got %d", c.queue)
+	switch c.output {
+	case "json", "csv", "table":
+	case "text", "none":
+		check(c.outputFile == "", "-output-file needs -output json, csv or table, got %q", c.output)
+	default:
+		check(false, "-output must be text, json, csv, table or none, got %q", c.output)
+	}
+
+	check(c.minWorkers >= 1, "-min-workers must be at least 1, got %d", c.minWorkers)
+	check(c.minWorkers <= c.maxWorkers, "-min-workers (%d) must not exceed -max-workers (%d)", c.minWorkers, c.maxWorkers)
+	check(c.taskTimeout >= 0, "-task-timeout must not be negative, got %v", c.taskTimeout)
+	check(c.maxAttempts >= 1, "-max-attempts must be at least 1, got %d", c.maxAttempts)
+	check(c.submitRate >= 0, "-submit-rate must not be negative, got %v", c.submitRate)
+	check(c.processRate >= 0, "-process-rate must not be negative, got %v", c.processRate)
+	check(c.rateBurst >= 1, "-rate-burst must be at least 1, got %d", c.rateBurst)
+	check(c.breakerThreshold >= 0, "-breaker-threshold must not be negative, got %d", c.breakerThreshold)
+	check(c.breakerCoolDown > 0, "-breaker-cooldown must be positive, got %v", c.breakerCoolDown)
+	check(c.ordered >= 0, "-ordered must not be negative, got %d", c.ordered)
+
+	if _, err := newLogger(c.logLevel, c.logFormat); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -1,10 +1,18 @@
 package main // The 'main' package indicates this is an executable program.
 
 import (
-	"fmt"     // Package for formatted I/O, used for printing output to the console.
-	"runtime" // Provides functions to interact with the Go runtime, e.g., NumCPU.
-	"sync"    // Package for synchronization primitives, e.g., WaitGroup.
-	"time"    // Package for time-related functions, used for measuring execution time.
+	"context"       // Package for cancellation signals propagated to every component.
+	"errors"        // Package for combining the errors of closing the write-ahead log.
+	"fmt"           // Package for formatted I/O, used for printing output to the console.
+	"log/slog"      // Package for the structured logs written by every component.
+	"net"           // Package for opening the metrics listener.
+	"net/http"      // Package for serving the metrics endpoint.
+	"os"            // Provides access to operating system signals such as os.Interrupt.
+	"os/signal"     // Package for turning OS signals into context cancellation.
+	"path/filepath" // Package for guessing the format of the -input file from its extension.
+	"strings"       // Package for comparing file extensions.
+	"sync"          // Package for synchronization primitives, e.g., WaitGroup.
+	"time"          // Package for time-related functions, used for measuring execution time.
 
 	// Import the 'exercise02workerpool' package, which contains all the core logic
 	// for the worker pool components (Task, Worker, Producer, Consumer, Pool).
@@ -14,71 +22,373 @@ import (
 
 // main is the entry point of the application.
 func main() {
+	// --- Command-Line Flags ---
+	// parseConfig (see config.go) documents every flag in -help and exits with
+	// status 2 if any of them is invalid.
+	cfg := parseConfig()
+	logger, _ := newLogger(cfg.logLevel, cfg.logFormat) // Already validated by parseConfig.
+
+	// Progress notes and the summary go to stdout with the results, unless the
+	// results are printed there as JSON, CSV or a table: then stdout carries
+	// nothing else, so it can be redirected to a file or another program.
+	info := os.Stdout
+	if cfg.outputFile == "" && cfg.output != "text" && cfg.output != "none" {
+		info = os.Stderr
+	}
+
 	// Record the start time to measure the total execution duration of the program.
 	startTime := time.Now()
 
+	// --- Cancellation ---
+	// The context is cancelled when the user presses Ctrl+C. Every component
+	// (Pool, Producer and Consumer) watches it, so an interrupted run stops
+	// generating tasks, abandons in-flight work and still shuts down cleanly.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// --- Task Source (optional) ---
+	// With -input the tasks are read from a file or stdin instead of generated.
+	// The file is opened before anything runs, so a bad path fails fast.
+	var source exercise02workerpool.TaskSource[int, any]
+	if cfg.input != "" {
+		var err error
+		if source, err = openSource(cfg.input, cfg.inputFormat); err != nil {
+			fmt.Fprintf(os.Stderr, "input: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// --- Result Sink ---
+	// The results are written in the -output format (see output.go), to stdout or
+	// to -output-file, which is created before anything runs so a bad path fails fast.
+	sink, err := newSink(cfg.output, cfg.outputFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "output: %v\n", err)
+		os.Exit(1)
+	}
+
 	// --- System Configuration ---
-	const numTasks = 1000 // Define the total number of tasks to be generated and processed.
-	// Determine the number of workers based on the number of available CPU cores.
+	numTasks := cfg.tasks // The total number of tasks to be generated and processed.
+	// The number of workers defaults to the number of available CPU cores.
 	// This is a common practice to optimize CPU-bound workloads, allowing one worker
 	// per core to maximize parallel execution without excessive context switching overhead.
-	numWorkers := runtime.NumCPU()
+	numWorkers := cfg.workers
 
 	// --- Worker Pool Setup ---
 	// Create a new instance of the worker Pool.
 	// The pool will manage the workers and the task/result channels.
-	pool := exercise02workerpool.NewPool(numWorkers)
+	// PrimeProcessor is the processing step every worker applies: it simulates
+	// the task's Complexity and checks whether its Data is prime.
+	// WithTaskTimeout gives every task a default deadline (none when the flag is 0),
+	// and WithRetryPolicy retries failed tasks with jittered exponential backoff.
+	// WithLogger makes the pool and its workers log every task attempt.
+	options := []exercise02workerpool.Option{
+		exercise02workerpool.WithLogger(logger),
+		exercise02workerpool.WithTaskTimeout(cfg.taskTimeout),
+		exercise02workerpool.WithRetryPolicy(exercise02workerpool.RetryPolicy{
+			MaxAttempts: cfg.maxAttempts,
+			Jitter:      0.2,
+		}),
+	}
+	if cfg.resultBuffer >= 0 {
+		// WithResultBuffer overrides the default capacity of pool.ResultChan.
+		options = append(options, exercise02workerpool.WithResultBuffer(cfg.resultBuffer))
+	}
+	if cfg.queue > 0 {
+		// WithQueueCapacity buffers pool.TaskChan, so the producer can run ahead of the workers.
+		options = append(options, exercise02workerpool.WithQueueCapacity(cfg.queue))
+	}
+	if cfg.deadLetters {
+		// WithDeadLetters routes permanently failed tasks to pool.DeadLetterChan.
+		options = append(options, exercise02workerpool.WithDeadLetters(numWorkers))
+	}
+	if cfg.processRate > 0 {
+		// WithRateLimit paces the attempts handed to the workers, retries included.
+		options = append(options, exercise02workerpool.WithRateLimit(exercise02workerpool.NewRateLimiter(cfg.processRate, cfg.rateBurst)))
+	}
+	if cfg.breakerThreshold > 0 {
+		// WithCircuitBreaker fails attempts with ErrCircuitOpen while the breaker is open.
+		breaker := exercise02workerpool.NewCircuitBreaker(exercise02workerpool.CircuitBreakerConfig{
+			FailureThreshold: cfg.breakerThreshold,
+			CoolDown:         cfg.breakerCoolDown,
+			OnStateChange: func(from, to exercise02workerpool.CircuitState) {
+				logger.Warn("circuit breaker state changed", "from", from, "to", to)
+			},
+		})
+		options = append(options, exercise02workerpool.WithCircuitBreaker(breaker))
+	}
+	if cfg.ordered > 0 {
+		// WithOrderedResults releases results by ascending task ID.
+		options = append(options, exercise02workerpool.WithOrderedResults(cfg.ordered))
+	}
+	if cfg.traceFile != "" {
+		// WithTracing records enqueue, dequeue, processing and delivery spans for
+		// every task. Closing the exporter flushes the last spans to the file.
+		exporter, err := exercise02workerpool.NewJSONFileExporter(cfg.traceFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "trace file: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() {
+			if err := exporter.Close(); err != nil {
+				fmt.Fprintf(os.Stderr, "trace file: %v\n", err)
+			}
+		}()
+		options = append(options, exercise02workerpool.WithTracing(exporter))
+	}
+	// With a write-ahead log, the tasks an earlier run accepted but never finished
+	// are sent again first, and the producer continues from the next unused ID.
+	var replay []exercise02workerpool.Task
+	firstID := 0
+	if cfg.walPath != "" {
+		wal, err := exercise02workerpool.OpenWAL(cfg.walPath, exercise02workerpool.SyncPolicy(cfg.walSync))
+		if err == nil {
+			replay, err = exercise02workerpool.PendingTasks[int, any](wal)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "write-ahead log: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() {
+			// Leave a compact log behind: just the tasks still unfinished.
+			if err := errors.Join(wal.Compact(), wal.Close()); err != nil {
+				fmt.Fprintf(os.Stderr, "write-ahead log: %v\n", err)
+			}
+		}()
+		firstID = min(wal.NextID(), numTasks)
+		fmt.Fprintf(info, "Write-ahead log: replaying %d unfinished tasks, then generating tasks %d to %d\n", len(replay), firstID, numTasks-1)
+		// WithWAL records every task accepted and delivered by the pool.
+		options = append(options, exercise02workerpool.WithWAL(wal))
+	}
+	pool := exercise02workerpool.NewPool(numWorkers, exercise02workerpool.PrimeProcessor{}, options...)
 
 	// A WaitGroup for the main function to synchronize the completion of the Producer
 	// and Consumer goroutines. This is distinct from the internal WaitGroup used by the Pool.
 	var wg sync.WaitGroup
 
+	// --- Start Metrics Endpoint (optional) ---
+	// The listener is opened before anything runs, so a bad address fails fast.
+	// It serves a fresh Stats snapshot on every scrape until the command exits.
+	if cfg.metricsAddr != "" {
+		listener, err := net.Listen("tcp", cfg.metricsAddr)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "metrics endpoint: %v\n", err)
+			os.Exit(1)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metricsHandler(pool))
+		server := &http.Server{Handler: mux}
+		defer server.Close()
+		go server.Serve(listener)
+		fmt.Fprintf(info, "Serving metrics at http://%s/metrics\n", listener.Addr())
+	}
+
 	// --- Start Workers ---
 	// Start the worker pool. This method launches 'numWorkers' goroutines,
-	// each running a Worker.Start() loop, and also a goroutine that waits for
+	// each running a Worker.Start(ctx) loop, and also a goroutine that waits for
 	// all workers to finish before closing the ResultChan.
-	pool.Start()
+	pool.Start(ctx)
 
 	// --- Start Producer ---
 	// Increment the main WaitGroup counter as the Producer will run in a separate goroutine.
 	wg.Add(1)
-	// Create a new Producer instance. It is given the total number of tasks to generate
-	// and the TaskChan from the pool to send tasks to.
-	producer := exercise02workerpool.NewProducer(numTasks, pool.TaskChan)
-	// Launch the producer's Start method in a new goroutine.
-	go func() {
-		// Defer wg.Done() ensures the main WaitGroup counter is decremented when
-		// the producer goroutine finishes its execution (after sending all tasks and closing TaskChan).
-		defer wg.Done()
-		producer.Start() // The producer starts generating and sending tasks.
-	}()
+	var limiter *exercise02workerpool.RateLimiter
+	if cfg.submitRate > 0 {
+		limiter = exercise02workerpool.NewRateLimiter(cfg.submitRate, cfg.rateBurst)
+	}
+	// Either producer reports how long it was blocked, which the autoscaler watches.
+	var blockReporter exercise02workerpool.BlockReporter
+	malformed := 0
+	if source != nil {
+		// A SourceProducer streams the tasks read from -input into the pool, skipping
+		// (and logging) malformed lines, and closes TaskChan once the input ends.
+		sourceProducer := exercise02workerpool.NewSourceProducer(source, pool.TaskChan)
+		sourceProducer.Logger = logger
+		sourceProducer.Limiter = limiter
+		blockReporter = sourceProducer
+		go func() {
+			defer wg.Done()
+			if err := sourceProducer.Start(ctx); err != nil {
+				fmt.Fprintf(os.Stderr, "input: %v\n", err)
+			}
+			malformed = sourceProducer.Malformed()
+		}()
+	} else {
+		// Create a new Producer instance. It is given the total number of tasks to generate
+		// and the TaskChan from the pool to send tasks to.
+		// A non-zero -seed makes the generated tasks the same on every run.
+		producer := exercise02workerpool.NewProducer(numTasks-firstID, pool.TaskChan)
+		if cfg.seed != 0 {
+			producer = exercise02workerpool.NewProducerWithSeed(numTasks-firstID, pool.TaskChan, cfg.seed)
+		}
+		producer.FirstID = firstID
+		producer.Logger = logger
+		producer.Limiter = limiter
+		// The tasks are drawn from the ranges given on the command line.
+		producer.MinData, producer.MaxData = cfg.minData, cfg.maxData
+		producer.MinComplexity, producer.MaxComplexity = cfg.minComplexity, cfg.maxComplexity
+		blockReporter = producer
+		// Launch the producer's Start method in a new goroutine.
+		go func() {
+			// Defer wg.Done() ensures the main WaitGroup counter is decremented when
+			// the producer goroutine finishes its execution (after sending all tasks and closing TaskChan).
+			defer wg.Done()
+			// Tasks replayed from the write-ahead log go first. A cancelled run
+			// stops the replay; the producer still closes TaskChan.
+		replaying:
+			for _, task := range replay {
+				select {
+				case pool.TaskChan <- task:
+				case <-ctx.Done():
+					break replaying
+				}
+			}
+			producer.Start(ctx) // The producer starts generating and sending tasks.
+		}()
+	}
+
+	// --- Start Autoscaler (optional) ---
+	// The autoscaler watches how long the producer is blocked, how busy the workers
+	// are and how long results take, and resizes the pool within the given bounds.
+	// It stops by itself once the pool has finished, so it is not part of the WaitGroup.
+	if cfg.autoscale {
+		autoscaler := exercise02workerpool.NewAutoscaler(pool, exercise02workerpool.AutoscalerConfig{
+			MinWorkers: cfg.minWorkers,
+			MaxWorkers: cfg.maxWorkers,
+			Producer:   blockReporter,
+			OnDecision: func(d exercise02workerpool.ScalingDecision) {
+				fmt.Fprintf(info, "Autoscaler: %v\n", d)
+			},
+		})
+		go autoscaler.Run(ctx)
+	}
+
+	// --- Start Progress Reporter (optional) ---
+	// Only one reader can take each result from pool.ResultChan, so with -progress
+	// a Broadcaster hands every result to both the consumer and the progress
+	// reporter. The consumer's subscription blocks, so it never misses a result;
+	// the reporter's drops results while it is busy, and counts them all the same.
+	var results <-chan exercise02workerpool.Task = pool.ResultChan
+	if cfg.progress > 0 {
+		broadcaster := exercise02workerpool.NewBroadcaster(pool.ResultChan)
+		broadcaster.Logger = logger
+		results = broadcaster.Subscribe(cap(pool.ResultChan), exercise02workerpool.SlowBlock).ResultChan
+		progress := broadcaster.Subscribe(1, exercise02workerpool.SlowDrop)
+		go broadcaster.Start(ctx) // Ends, closing both subscriptions, once the pool has finished.
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reportProgress(progress, cfg.progress, info)
+		}()
+	}
 
 	// --- Start Consumer ---
 	// Increment the main WaitGroup counter for the Consumer goroutine.
 	wg.Add(1)
 	// Create a new Consumer instance. It is given the ResultChan from the pool
-	// to receive processed tasks from.
-	consumer := exercise02workerpool.NewConsumer(pool.ResultChan)
-	// Launch the consumer's Start method in a new goroutine.
+	// (or its subscription to the pool's results) to receive processed tasks from,
+	// and writes them to the sink.
+	consumer := exercise02workerpool.NewConsumer(results)
+	consumer.Sink = sink
+	consumer.Logger = logger
+	// Launch the consumer's Start method in a new goroutine. Its error, read after
+	// wg.Wait(), tells whether every result could be written.
+	var sinkErr error
 	go func() {
 		// Defer wg.Done() ensures the main WaitGroup counter is decremented when
 		// the consumer goroutine finishes (after the ResultChan is closed and drained).
 		defer wg.Done()
-		consumer.Start() // The consumer starts receiving and displaying results.
+		sinkErr = consumer.Start(ctx) // The consumer starts receiving and displaying results.
 	}()
 
+	// --- Start Dead-Letter Reader (optional) ---
+	// The dead-letter channel must be drained just like the ResultChan, otherwise
+	// a full buffer would hold up the delivery of every other result.
+	failedTasks := 0
+	if pool.DeadLetterChan != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range pool.DeadLetterChan {
+				failedTasks++
+				fmt.Fprintf(info, "Dead letter: task %d (Data = %d) failed after %d attempts: %v\n",
+					task.ID, task.Data, task.Attempts, task.Errors)
+			}
+		}()
+	}
+
 	// --- Await Completion ---
 	// wg.Wait() blocks the main goroutine until all goroutines associated with
 	// the main WaitGroup (i.e., Producer and Consumer) have called wg.Done().
 	// This ensures that all tasks are generated, processed, and consumed before
 	// the main function proceeds to display the summary or exits.
 	wg.Wait()
+	if sinkErr != nil {
+		fmt.Fprintf(os.Stderr, "Writing the results failed: %v\n", sinkErr) // Some results are missing from the output.
+	}
 
 	// --- Display Execution Summary ---
 	// Calculate the total time elapsed since the program started.
 	elapsedTime := time.Since(startTime)
-	fmt.Printf("\nSystem Summary:\n")
-	fmt.Printf("Total tasks processed: %d\n", numTasks)   // This refers to the number of tasks the producer was configured to generate.
-	fmt.Printf("Number of workers: %d\n", numWorkers)     // Displays the number of workers utilized.
-	fmt.Printf("Total execution time: %v\n", elapsedTime) // Displays the total time taken for the entire process.
+	fmt.Fprintf(info, "\nSystem Summary:\n")
+	if source != nil {
+		fmt.Fprintf(info, "Total tasks processed: %d\n", pool.Stats().Submitted) // The tasks read from -input.
+		fmt.Fprintf(info, "Malformed input lines skipped: %d\n", malformed)
+	} else {
+		fmt.Fprintf(info, "Total tasks processed: %d\n", numTasks) // This refers to the number of tasks the producer was configured to generate.
+	}
+	fmt.Fprintf(info, "Number of workers: %d\n", numWorkers) // Displays the number of workers utilized.
+	if cfg.autoscale {
+		fmt.Fprintf(info, "Final number of workers: %d\n", pool.Size()) // Displays where the autoscaler left the pool.
+	}
+	fmt.Fprintf(info, "Total execution time: %v\n", elapsedTime) // Displays the total time taken for the entire process.
+	if cfg.deadLetters {
+		fmt.Fprintf(info, "Dead-lettered tasks: %d\n", failedTasks) // Displays how many tasks failed permanently.
+	}
+	fmt.Fprintf(info, "Recovered panics: %d\n", pool.Panics()) // Displays how many attempts panicked inside the Processor.
+
+	// Pool.Stats() gives the counters and latency histograms gathered during the run.
+	stats := pool.Stats()
+	fmt.Fprintf(info, "Completed / failed / retried: %d / %d / %d\n", stats.Completed, stats.Failed, stats.Retried)
+	if cfg.breakerThreshold > 0 {
+		fmt.Fprintf(info, "Circuit breaker: %v, %d attempts rejected\n", stats.Circuit, stats.CircuitRejected)
+	}
+	fmt.Fprintf(info, "Queue wait: mean %v, p95 <= %v\n", stats.QueueWait.Mean().Round(time.Microsecond), stats.QueueWait.Quantile(0.95))
+	fmt.Fprintf(info, "Processing: mean %v, p95 <= %v\n", stats.Processing.Mean().Round(time.Microsecond), stats.Processing.Quantile(0.95))
+}
+
+// newLogger builds the logger selected by the -log-level and -log-format flags.
+func newLogger(level, format string) (*slog.Logger, error) {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		return nil, fmt.Errorf("invalid -log-level %q: %w", level, err)
+	}
+
+	opts := &slog.HandlerOptions{Level: minLevel}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+	default:
+		return nil, fmt.Errorf("invalid -log-format %q: want text or json", format)
+	}
+}
+
+// openSource opens the -input task source: the file at path, or stdin for "-",
+// in the given format, which is guessed from the file extension when empty.
+func openSource(path, format string) (exercise02workerpool.TaskSource[int, any], error) {
+	if format == "" {
+		format = "jsonl"
+		if strings.EqualFold(filepath.Ext(path), ".csv") {
+			format = "csv"
+		}
+	}
+	if path == "-" {
+		return exercise02workerpool.NewStdinSource(format)
+	}
+	if format == "csv" {
+		return exercise02workerpool.OpenCSVSource(path)
+	}
+	return exercise02workerpool.OpenJSONLinesSource(path)
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"    // Package for buffering the response while the metrics are written.
+	"fmt"      // Package for formatting the exposition lines.
+	"net/http" // Package for serving the metrics endpoint.
+	"sort"     // Package for listing workers in a stable order.
+	"strconv"  // Package for formatting bucket bounds as label values.
+	"time"     // Package for converting durations to seconds.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// metricsHandler serves the pool's Stats in the Prometheus text exposition
+// format (version 0.0.4), so a Prometheus server can scrape a running command.
+// Every request takes a fresh snapshot of the pool.
+func metricsHandler(pool *exercise02workerpool.Pool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats := pool.Stats()
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		out := bufio.NewWriter(w)
+		defer out.Flush()
+
+		// Throughput and errors: counters that only ever grow during a run.
+		writeMetric(out, "workerpool_tasks_submitted_total", "counter", "Tasks accepted by the pool.", float64(stats.Submitted))
+		writeMetric(out, "workerpool_tasks_completed_total", "counter", "Tasks finished successfully.", float64(stats.Completed))
+		writeMetric(out, "workerpool_tasks_failed_total", "counter", "Tasks that failed for good, after any retries.", float64(stats.Failed))
+		writeMetric(out, "workerpool_tasks_retried_total", "counter", "Retries scheduled by the retry policy.", float64(stats.Retried))
+		writeMetric(out, "workerpool_task_panics_total", "counter", "Task attempts whose processor panicked.", float64(stats.Panics))
+
+		// Queue depth: accepted tasks that no worker is processing right now are
+		// waiting for a free worker or for their retry backoff to elapse.
+		writeMetric(out, "workerpool_tasks_pending", "gauge", "Accepted tasks not finished yet.", float64(stats.Pending))
+		writeMetric(out, "workerpool_tasks_queued", "gauge", "Accepted tasks waiting for a worker or a retry.", float64(max(stats.Pending-int64(stats.InFlight), 0)))
+		writeMetric(out, "workerpool_results_queued", "gauge", "Results buffered in the result channel.", float64(len(pool.ResultChan)))
+		writeMetric(out, "workerpool_input_queued", "gauge", "Tasks buffered in the task queue, not accepted yet.", float64(len(pool.TaskChan)))
+
+		// Worker utilisation: the share of workers busy right now, and the busy
+		// time of each worker, whose rate gives its utilisation over any window.
+		writeMetric(out, "workerpool_workers", "gauge", "Configured number of workers.", float64(stats.Workers))
+		writeMetric(out, "workerpool_tasks_in_flight", "gauge", "Tasks being processed right now.", float64(stats.InFlight))
+		utilisation := 0.0
+		if stats.Workers > 0 {
+			utilisation = min(float64(stats.InFlight)/float64(stats.Workers), 1)
+		}
+		writeMetric(out, "workerpool_worker_utilisation", "gauge", "Fraction of the workers processing a task right now.", utilisation)
+		writeWorkerBusy(out, stats.WorkerBusy)
+
+		// Circuit breaker: its state as a number (0 closed, 1 open, 2 half-open).
+		writeMetric(out, "workerpool_circuit_state", "gauge", "Circuit breaker state: 0 closed, 1 open, 2 half-open.", float64(stats.Circuit))
+		writeMetric(out, "workerpool_circuit_rejected_total", "counter", "Task attempts rejected by the open circuit breaker.", float64(stats.CircuitRejected))
+
+		// Latency histograms.
+		writeHistogram(out, "workerpool_queue_wait_seconds", "Time from a task being queued to a worker taking it.", stats.QueueWait)
+		writeHistogram(out, "workerpool_processing_seconds", "Time a worker spent processing each attempt.", stats.Processing)
+	})
+}
+
+// writeMetric writes a single unlabelled sample with its HELP and TYPE lines.
+func writeMetric(out *bufio.Writer, name, kind, help string, value float64) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", name, help, name, kind, name, formatFloat(value))
+}
+
+// writeWorkerBusy writes the busy time of every worker, labelled by worker ID.
+func writeWorkerBusy(out *bufio.Writer, busy map[int]time.Duration) {
+	const name = "workerpool_worker_busy_seconds_total"
+	fmt.Fprintf(out, "# HELP %s Time each worker spent processing tasks.\n# TYPE %s counter\n", name, name)
+
+	ids := make([]int, 0, len(busy))
+	for id := range busy {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		fmt.Fprintf(out, "%s{worker=\"%d\"} %s\n", name, id, formatFloat(busy[id].Seconds()))
+	}
+}
+
+// writeHistogram writes h as a Prometheus histogram. Prometheus buckets are
+// cumulative, so each one counts every observation up to its bound.
+func writeHistogram(out *bufio.Writer, name, help string, h exercise02workerpool.Histogram) {
+	fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+
+	var cumulative int64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		fmt.Fprintf(out, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(bound.Seconds()), cumulative)
+	}
+	fmt.Fprintf(out, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+	fmt.Fprintf(out, "%s_sum %s\n", name, formatFloat(h.Sum.Seconds()))
+	fmt.Fprintf(out, "%s_count %d\n", name, h.Count)
+}
+
+// formatFloat formats v the way Prometheus expects sample values and bounds.
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"  // Package for printing the progress reports.
+	"io"   // Package for the writer the progress is reported to.
+	"os"   // Package for standard output.
+	"time" // Package for the progress interval and the rate of results.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// discardSink drops every result, for -output none.
+type discardSink struct{}
+
+func (discardSink) Write(exercise02workerpool.Task) error { return nil }
+func (discardSink) Close() error                          { return nil }
+
+// newSink returns the sink for the -output format, writing to the file at path,
+// or to stdout when path is empty. A nil sink selects the Consumer's own text
+// lines on stdout.
+func newSink(format, path string) (exercise02workerpool.ResultSink[int, any], error) {
+	if path == "" {
+		switch format {
+		case "json":
+			return exercise02workerpool.NewJSONLinesSink(os.Stdout), nil
+		case "csv":
+			return exercise02workerpool.NewCSVSink(os.Stdout), nil
+		case "table":
+			return exercise02workerpool.NewTableSink(os.Stdout), nil
+		}
+	} else {
+		switch format {
+		case "json":
+			return exercise02workerpool.CreateJSONLinesSink(path)
+		case "csv":
+			return exercise02workerpool.CreateCSVSink(path)
+		case "table":
+			return exercise02workerpool.CreateTableSink(path)
+		}
+	}
+	if format == "none" {
+		return discardSink{}, nil
+	}
+	return nil, nil // "text"
+}
+
+// reportProgress prints, every interval, how many results have arrived on the
+// subscription and at what rate, until the subscription's channel is closed.
+// Results the subscription dropped while the reporter was busy still count.
+func reportProgress(progress *exercise02workerpool.Subscription, interval time.Duration, out io.Writer) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	start := time.Now()
+	received := 0
+	for {
+		select {
+		case _, ok := <-progress.ResultChan:
+			if !ok {
+				return
+			}
+			received++
+		case <-ticker.C:
+			elapsed := time.Since(start)
+			total := received + progress.Dropped()
+			fmt.Fprintf(out, "Progress: %d results after %v, %.1f results/s\n",
+				total, elapsed.Round(100*time.Millisecond), float64(total)/elapsed.Seconds())
+		}
+	}
+}
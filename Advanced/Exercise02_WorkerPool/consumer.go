@@ -1,13 +1,85 @@
 package exercise02workerpool
 
 import (
-	"fmt" // Package for formatted I/O, used for printing results to the console.
+	"context"  // Package for cancellation signals that stop the consumer early.
+	"errors"   // Package for combining the errors of writing and closing the sink.
+	"fmt"      // Package for formatted I/O, used for printing results to the console.
+	"log/slog" // Package for the structured events logged for every result.
+	"os"       // Package for standard output, where Consumer prints by default.
 )
 
-// Consumer is responsible for receiving and processing the results from the worker pool.
-// It reads processed tasks from the result channel and displays their outcome.
+// TypedConsumer is responsible for receiving the results from the worker pool.
+// It reads processed tasks from the result channel, passes each one to Handle
+// and writes it to Sink.
+type TypedConsumer[In, Out any] struct {
+	ResultChan <-chan TypedTask[In, Out] // A receive-only channel from which the consumer receives processed tasks.
+	Handle     func(TypedTask[In, Out])  // Called for every task received, in the consumer's goroutine. Nil does nothing.
+	Sink       ResultSink[In, Out]       // Receives every task after Handle, and is closed by Start. Nil writes nothing.
+	Logger     *slog.Logger              // Receives an event for every result. Nil logs nothing.
+}
+
+// NewTypedConsumer creates and returns a new TypedConsumer instance.
+// It initializes the consumer with the channel from which it will receive results
+// and the function that handles each of them.
+func NewTypedConsumer[In, Out any](resultChan <-chan TypedTask[In, Out], handle func(TypedTask[In, Out])) *TypedConsumer[In, Out] {
+	return &TypedConsumer[In, Out]{
+		ResultChan: resultChan, // Assigns the result input channel.
+		Handle:     handle,     // Assigns the result handler.
+	}
+}
+
+// Start begins the consumer's main loop and returns the number of tasks received.
+// This method is designed to be run in its own goroutine. It returns when the
+// ResultChan is closed and drained, or when ctx is cancelled, after closing Sink.
+// The error reports the first result the Sink failed to write, and the failure
+// to close it. The consumer stops writing after a failure, but keeps receiving
+// the results so the pool is never held up.
+func (c *TypedConsumer[In, Out]) Start(ctx context.Context) (int, error) {
+	processed := 0 // Counter to keep track of the total number of tasks processed by this consumer.
+	logger := loggerOrDiscard(c.Logger)
+	var writeErr error
+
+	for {
+		var task TypedTask[In, Out]
+		var ok bool
+		select {
+		case task, ok = <-c.ResultChan:
+		case <-ctx.Done():
+			// The run was aborted. Workers never block on a cancelled context,
+			// so it is safe to stop reading even if results remain buffered.
+			ok = false
+		}
+		if !ok {
+			// Either the ResultChan was closed (by the pool) and all values have been
+			// received, or the context was cancelled: in both cases we are done.
+			logger.InfoContext(ctx, "consumer finished", "received", processed, "cancelled", ctx.Err() != nil)
+			if c.Sink == nil {
+				return processed, writeErr
+			}
+			// Closing the sink writes out the results it still buffers.
+			return processed, errors.Join(writeErr, c.Sink.Close())
+		}
+
+		processed++ // Increment the counter for each task received.
+		logger.LogAttrs(ctx, slog.LevelDebug, "result received",
+			slog.Int("task", task.ID), slog.Int("attempts", task.Attempts), slog.Any("error", task.Err))
+		if c.Handle != nil {
+			c.Handle(task)
+		}
+		if c.Sink != nil && writeErr == nil {
+			if err := c.Sink.Write(task); err != nil {
+				writeErr = err
+				logger.ErrorContext(ctx, "writing results failed", "task", task.ID, "error", err)
+			}
+		}
+	}
+}
+
+// Consumer receives prime-checking results from the worker pool and displays their outcome.
 type Consumer struct {
-	ResultChan <-chan Task // A receive-only channel from which the consumer receives processed tasks.
+	ResultChan <-chan Task          // A receive-only channel from which the consumer receives processed tasks.
+	Sink       ResultSink[int, any] // Receives every result. Nil prints them to stdout, followed by a count.
+	Logger     *slog.Logger         // Receives an event for every result. Nil logs nothing.
 }
 
 // NewConsumer creates and returns a new Consumer instance.
@@ -19,22 +91,34 @@ func NewConsumer(resultChan <-chan Task) *Consumer {
 }
 
 // Start begins the consumer's main loop for processing results.
-// This method is designed to be run in its own goroutine.
-func (c *Consumer) Start() {
-	processed := 0 // Counter to keep track of the total number of tasks processed by this consumer.
-
-	// The 'for range' loop over a channel will continuously receive values
-	// until the channel is closed. Once the channel is closed (by the Pool)
-	// and all values have been received, the loop will terminate gracefully.
-	for task := range c.ResultChan {
-		processed++ // Increment the counter for each task received.
+// This method is designed to be run in its own goroutine. It returns when the
+// ResultChan is closed and drained, or when ctx is cancelled, with the error of
+// the Sink, if any (see TypedConsumer.Start).
+func (c *Consumer) Start(ctx context.Context) error {
+	failed := 0 // Counter for tasks that came back with an error (e.g. cancelled or timed out).
 
-		// Print the details of the processed task to the console.
-		// This includes the task ID, its original data, and the calculated result (e.g., isPrime).
-		fmt.Printf("Task   %d\t Data = %d\t isPrime = %v\n", task.ID, task.Data, task.Result)
+	sink := c.Sink
+	if sink == nil {
+		// Without a sink, every result is printed to the console as a line of text.
+		sink = primeTextSink{w: os.Stdout}
 	}
+	consumer := NewTypedConsumer(c.ResultChan, func(task Task) {
+		if task.Err != nil {
+			failed++
+		}
+	})
+	consumer.Sink = sink
+	consumer.Logger = c.Logger
+	processed, err := consumer.Start(ctx)
 
 	// After the ResultChan is closed and all results have been consumed,
-	// print a summary indicating the total number of tasks processed.
-	fmt.Printf("Processed %d tasks\n", processed)
+	// print a summary indicating the total number of tasks processed. It only
+	// goes with the text on stdout, where it cannot corrupt a sink's format.
+	if c.Sink == nil {
+		fmt.Printf("Processed %d tasks\n", processed)
+		if failed > 0 {
+			fmt.Printf("Failed %d tasks\n", failed)
+		}
+	}
+	return err
 }
@@ -0,0 +1,273 @@
+package exercise02workerpool
+
+import (
+	"context"  // Package for cancellation signals that end the dispatcher and the collector.
+	"errors"   // Package for recognising recovered panics.
+	"log/slog" // Package for the structured events logged about retries and failures.
+	"time"     // Package for retry backoff timers.
+)
+
+// dispatch moves tasks from TaskChan, and retries whose backoff has elapsed, to
+// the workers. It closes the work channel, which makes the workers exit, once
+// TaskChan has been closed and every accepted task has been finished for good,
+// or as soon as ctx is cancelled.
+//
+// With ordered results, a new task is only accepted once a slot of the reorder
+// window is free; retries already own theirs and are dispatched regardless.
+func (p *TypedPool[In, Out]) dispatch(ctx context.Context) {
+	defer close(p.work)
+	defer close(p.stopped) // Submit must not report tasks sent from now on as accepted.
+
+	input := p.TaskChan // Set to nil once closed, which disables its select case.
+	haveSlot := p.slots == nil
+	for {
+		// Waiting for outstanding tasks matters: a task that is still being
+		// processed may fail and need to be dispatched again. Tasks that ordered
+		// results hold back for a missing ID are finished, and the collector
+		// flushes them. Outstanding is read first: it never drops below waiting.
+		outstanding := p.outstanding.Load()
+		if input == nil && outstanding == p.reorder.Waiting() {
+			return
+		}
+
+		// Only one of these is enabled at a time: a slot must be held before
+		// the input is read. Nil channels disable their select case.
+		accept, acquire := input, p.slots
+		if haveSlot {
+			acquire = nil
+		} else {
+			accept = nil
+		}
+
+		var task TypedTask[In, Out]
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.settled:
+			continue // Re-check the exit condition.
+		case acquire <- struct{}{}:
+			haveSlot = true
+			continue
+		case task = <-p.retries:
+		case t, ok := <-accept:
+			if !ok {
+				input = nil
+				continue
+			}
+			p.outstanding.Add(1)
+			p.metrics.submitted.Add(1)
+			task = t
+			haveSlot = p.slots == nil
+			if p.options.tracer != nil {
+				task.traceAccepted(time.Now())
+			}
+			if p.options.wal != nil {
+				if err := walSubmit(p.options.wal, task); err != nil {
+					p.options.logger.ErrorContext(ctx, "write-ahead log failed", "task", task.ID, "error", err)
+				}
+			}
+		}
+		task.queuedAt = time.Now()
+
+		// With a rate limit, wait for a token before the attempt may start.
+		if p.options.limiter.Wait(ctx) != nil {
+			return
+		}
+
+		// Hand the task to the next free worker. The work channel is unbuffered,
+		// so this blocks until a worker is ready, keeping the backpressure on
+		// whoever sends to TaskChan.
+		select {
+		case p.work <- task:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// collect receives every task the workers finish. Failed tasks that the retry
+// policy allows are scheduled for another attempt; all others are delivered to
+// ResultChan, or to DeadLetterChan if they failed and dead letters are enabled,
+// or resolve their future. Both channels are closed once the workers have all
+// returned, and the futures of tasks a cancelled run dropped are resolved.
+func (p *TypedPool[In, Out]) collect(ctx context.Context) {
+	defer close(p.done)
+	if p.DeadLetterChan != nil {
+		defer close(p.DeadLetterChan)
+	}
+	// Once all workers are done, close the ResultChan.
+	// This signals to the consumer (and any other goroutines reading from ResultChan)
+	// that no more results will be sent, allowing their receive loops to exit gracefully.
+	defer close(p.ResultChan)
+
+	policy := p.options.retry
+	for task := range p.completed {
+		var panicErr *PanicError
+		if errors.As(task.Err, &panicErr) {
+			p.panics.Add(1)
+		}
+		p.metrics.observeAttempt(task.takenAt.Sub(task.queuedAt), task.processedAt.Sub(task.takenAt))
+		if p.options.tracer != nil {
+			task.traceAttempt()
+		}
+
+		// A cancelled run is over: nothing is retried, whatever the policy says.
+		if task.Err != nil && ctx.Err() == nil && policy.ShouldRetry(task.Err, task.Attempts) {
+			p.metrics.retried.Add(1)
+			backoff := policy.Backoff(task.Attempts)
+			p.options.logger.LogAttrs(ctx, slog.LevelInfo, "task retry scheduled",
+				slog.Int("task", task.ID), slog.Int("attempt", task.Attempts),
+				slog.Duration("backoff", backoff), slog.Any("error", task.Err))
+			p.scheduleRetry(ctx, task, backoff)
+			continue
+		}
+		p.finish(ctx, task)
+	}
+
+	// Ordered results may still hold tasks back for a missing ID: the input
+	// ended without it, or a cancelled run dropped it. Deliver them in order.
+	if p.reorder != nil {
+		for _, task := range p.reorder.flush() {
+			p.deliver(ctx, task)
+		}
+	}
+
+	// The workers can all return on a cancelled ctx before the dispatcher has
+	// noticed it. Waiting for the dispatcher (which returns promptly then too)
+	// means no Submit can succeed once ResultChan is closed.
+	<-p.stopped
+
+	// Nothing else will be delivered: a future still pending belongs to a task
+	// the cancelled run dropped, or that was only queued in TaskChan.
+	p.futures.abandon(ErrPoolStopped)
+
+	p.options.logger.InfoContext(ctx, "pool finished",
+		"submitted", p.metrics.submitted.Load(), "completed", p.metrics.completed.Load(),
+		"failed", p.metrics.failed.Load(), "retried", p.metrics.retried.Load())
+}
+
+// finish handles a task that will not be attempted again: it counts it and
+// delivers it, or, with ordered results, holds it back until every lower ID
+// has been delivered or given up on.
+func (p *TypedPool[In, Out]) finish(ctx context.Context, task TypedTask[In, Out]) {
+	if task.Err != nil {
+		p.metrics.failed.Add(1)
+		p.options.logger.LogAttrs(ctx, errorLevel(task.Err), "task failed",
+			slog.Int("task", task.ID), slog.Int("attempts", task.Attempts),
+			slog.Any("error", task.Err), slog.Bool("dead_letter", p.DeadLetterChan != nil))
+	} else {
+		p.metrics.completed.Add(1)
+	}
+	task.finishedAt = time.Now()
+
+	if p.reorder == nil {
+		p.deliver(ctx, task)
+		return
+	}
+	if !p.reorder.add(task) {
+		// Its ID was already released, skipped or held: it cannot take its
+		// place anymore, so it is not held back at all.
+		p.options.logger.WarnContext(ctx, "task released out of order", "task", task.ID)
+		p.deliver(ctx, task)
+		<-p.slots // Frees the slot taken by the dispatcher when it accepted task.
+		return
+	}
+	p.wake() // The dispatcher may be waiting for this task to be finished.
+	for {
+		next, ok := p.reorder.pop()
+		if !ok {
+			missing, stalled := p.reorder.skip()
+			if !stalled {
+				return
+			}
+			p.options.logger.WarnContext(ctx, "ordered results skipped a missing task", "task", missing)
+			continue
+		}
+		p.deliver(ctx, next)
+		<-p.slots // Frees the slot taken by the dispatcher when it accepted next.
+	}
+}
+
+// deliver sends a finished task to ResultChan, or to DeadLetterChan if it failed
+// and dead letters are enabled, or resolves its future instead, and records that
+// it is settled.
+func (p *TypedPool[In, Out]) deliver(ctx context.Context, task TypedTask[In, Out]) {
+	var delivered bool
+	if f := task.future; f != nil {
+		// A task submitted with SubmitFuture bypasses the channels; resolving
+		// never blocks, so it is delivered even after ctx is cancelled.
+		p.futures.remove(f)
+		f.resolve(task, task.Err)
+		delivered = true
+	} else if task.Err != nil && p.DeadLetterChan != nil {
+		delivered = send(ctx, p.DeadLetterChan, task)
+	} else {
+		delivered = send(ctx, p.ResultChan, task)
+	}
+	// A task that failed because the run was cancelled, or that could not be
+	// delivered, stays pending in the write-ahead log, to be replayed.
+	if p.options.wal != nil && delivered && (task.Err == nil || ctx.Err() == nil) {
+		if err := p.options.wal.complete(task.ID); err != nil {
+			p.options.logger.ErrorContext(ctx, "write-ahead log failed", "task", task.ID, "error", err)
+		}
+	}
+	if p.options.tracer != nil {
+		spans := task.deliveredSpans(task.finishedAt, time.Now())
+		if err := p.options.tracer.ExportSpans(spans); err != nil {
+			p.options.logger.WarnContext(ctx, "span export failed", "task", task.ID, "error", err)
+		}
+	}
+	p.outstanding.Add(-1)
+	p.wake()
+}
+
+// wake signals the dispatcher without ever blocking: one pending signal is
+// enough for it to re-check whether the run is complete.
+func (p *TypedPool[In, Out]) wake() {
+	select {
+	case p.settled <- struct{}{}:
+	default:
+	}
+}
+
+// scheduleRetry hands task back to the dispatcher after delay. The wait happens
+// in its own goroutine, so neither the collector nor any worker is held up by
+// the backoff. If ctx is cancelled first, the task is dropped.
+func (p *TypedPool[In, Out]) scheduleRetry(ctx context.Context, task TypedTask[In, Out], delay time.Duration) {
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+
+		select {
+		case p.retries <- task:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// send delivers v to ch and reports whether it was sent.
+// While the context is alive it blocks like a plain send, preserving backpressure.
+// Once the context is cancelled, v is only delivered if the channel's buffer
+// still has room; otherwise it is dropped so the sender can never block forever
+// on a receiver that has already stopped reading.
+func send[T any](ctx context.Context, ch chan<- T, v T) bool {
+	select {
+	case ch <- v:
+		return true
+	case <-ctx.Done():
+	}
+
+	// The context is done, but a free slot in the buffer is still a valid way out.
+	select {
+	case ch <- v:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,121 @@
+package exercise02workerpool
+
+import (
+	"context" // Package for cancellation signals that stop waiting for a result.
+	"sync"    // Package for the mutex guarding the pending futures.
+)
+
+// TypedFuture is the pending result of a single task submitted with
+// SubmitFuture. It is resolved by the pool once the task is finished for good,
+// after any retries, instead of the task being delivered to ResultChan or
+// DeadLetterChan, so the caller awaits exactly its own result while other
+// consumers keep reading the channels.
+type TypedFuture[In, Out any] struct {
+	done chan struct{} // Closed once the future is resolved.
+	once sync.Once
+	task TypedTask[In, Out] // The finished task; set before done is closed.
+	err  error              // The task's Err, or why it was never finished.
+}
+
+// Future is a TypedFuture for the prime-checking Task.
+type Future = TypedFuture[int, any]
+
+// SubmitFuture submits task like Submit and returns the future of its result.
+// The error is Submit's: when it is not nil the task was not accepted and there
+// is no future. A task accepted by a run that is then cancelled may never be
+// finished; its future is resolved with ErrPoolStopped once the pool is done.
+func (p *TypedPool[In, Out]) SubmitFuture(ctx context.Context, task TypedTask[In, Out]) (*TypedFuture[In, Out], error) {
+	f := &TypedFuture[In, Out]{done: make(chan struct{})}
+	if !p.futures.add(f) {
+		return nil, ErrPoolStopped
+	}
+	task.future = f
+	if err := p.Submit(ctx, task); err != nil {
+		p.futures.remove(f)
+		return nil, err
+	}
+	return f, nil
+}
+
+// Done returns a channel that is closed once the future is resolved, so that
+// it can be awaited in a select together with other events.
+func (f *TypedFuture[In, Out]) Done() <-chan struct{} {
+	return f.done
+}
+
+// Get waits until the task is finished and returns its Result and Err. It
+// returns ErrPoolStopped if the run ended without finishing the task, and
+// ctx.Err() if ctx is done first, in which case the future stays pending and
+// Get may be called again.
+func (f *TypedFuture[In, Out]) Get(ctx context.Context) (Out, error) {
+	select {
+	case <-f.done:
+		return f.task.Result, f.err
+	case <-ctx.Done():
+		var zero Out
+		return zero, ctx.Err()
+	}
+}
+
+// Task returns the finished task, with its Attempts and the Errors of failed
+// attempts, once Done is closed; before that it returns the zero value. For a
+// task the run never finished, only the error of Get is set.
+func (f *TypedFuture[In, Out]) Task() TypedTask[In, Out] {
+	select {
+	case <-f.done:
+		return f.task
+	default:
+		return TypedTask[In, Out]{}
+	}
+}
+
+// resolve sets the future's outcome. Only the first call has an effect.
+func (f *TypedFuture[In, Out]) resolve(task TypedTask[In, Out], err error) {
+	f.once.Do(func() {
+		task.future = nil // The task may be submitted again without resolving f twice.
+		f.task, f.err = task, err
+		close(f.done)
+	})
+}
+
+// futureSet holds the futures whose task has not been delivered yet, so that
+// the collector can still resolve the ones a cancelled run dropped. The zero
+// value is an empty set.
+type futureSet[In, Out any] struct {
+	mu      sync.Mutex
+	pending map[*TypedFuture[In, Out]]struct{}
+	closed  bool // Set once the run is over; no future can be added after that.
+}
+
+// add records f as pending, and reports false if the run is already over.
+func (s *futureSet[In, Out]) add(f *TypedFuture[In, Out]) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return false
+	}
+	if s.pending == nil {
+		s.pending = make(map[*TypedFuture[In, Out]]struct{})
+	}
+	s.pending[f] = struct{}{}
+	return true
+}
+
+// remove forgets f, once it is resolved or its task was not accepted.
+func (s *futureSet[In, Out]) remove(f *TypedFuture[In, Out]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, f)
+}
+
+// abandon resolves every pending future with err and closes the set. It is
+// called by the collector once nothing else will be delivered.
+func (s *futureSet[In, Out]) abandon(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	for f := range s.pending {
+		f.resolve(TypedTask[In, Out]{}, err)
+	}
+	s.pending = nil
+}
@@ -0,0 +1,104 @@
+package exercise02workerpool_test
+
+import (
+	"context" // Used to run the pool and wait for the futures.
+	"errors"  // Used to build failed tasks and check the errors of the futures.
+	"testing" // The testing package is required for tests.
+	"time"    // Used for test timeouts.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// TestPoolSubmitFuture checks that every future receives the result of its own
+// task, and that those tasks bypass ResultChan while the others still use it.
+func TestPoolSubmitFuture(t *testing.T) {
+	double := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		if task.Data < 0 {
+			return nil, errors.New("negative")
+		}
+		return task.Data * 2, nil
+	})
+	pool := exercise02workerpool.NewPool(4, double)
+	ctx := context.Background()
+	pool.Start(ctx)
+
+	received := make(chan int)
+	go func() {
+		n := 0
+		for range pool.ResultChan {
+			n++
+		}
+		received <- n
+	}()
+
+	futures := make([]*exercise02workerpool.Future, 10)
+	for i := range futures {
+		if err := pool.Submit(ctx, exercise02workerpool.Task{ID: 100 + i, Data: i}); err != nil {
+			t.Fatalf("Submit returned %v", err)
+		}
+		future, err := pool.SubmitFuture(ctx, exercise02workerpool.Task{ID: i, Data: i})
+		if err != nil {
+			t.Fatalf("SubmitFuture returned %v", err)
+		}
+		futures[i] = future
+	}
+	failed, err := pool.SubmitFuture(ctx, exercise02workerpool.Task{ID: 10, Data: -1})
+	if err != nil {
+		t.Fatalf("SubmitFuture returned %v", err)
+	}
+
+	for i := len(futures) - 1; i >= 0; i-- {
+		result, err := futures[i].Get(ctx)
+		if err != nil || result != i*2 {
+			t.Errorf("future %d got %v, %v, want %d", i, result, err, i*2)
+		}
+	}
+	<-failed.Done()
+	if _, err := failed.Get(ctx); err == nil || failed.Task().ID != 10 || failed.Task().Attempts != 1 {
+		t.Errorf("the failed future got error %v and task %+v", err, failed.Task())
+	}
+
+	pool.CloseInput()
+	if n := <-received; n != 10 {
+		t.Errorf("ResultChan carried %d results, want only the 10 submitted without a future", n)
+	}
+}
+
+// TestPoolSubmitFutureCancelled checks that the futures of tasks a cancelled
+// run never finished are resolved with an error instead of hanging.
+func TestPoolSubmitFutureCancelled(t *testing.T) {
+	blocked := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	pool := exercise02workerpool.NewPool(1, blocked, exercise02workerpool.WithQueueCapacity(1))
+	ctx, cancel := context.WithCancel(context.Background())
+	pool.Start(ctx)
+
+	// One task is processed, one waits for the worker and the last one is queued.
+	var futures []*exercise02workerpool.Future
+	for i := 0; i < 3; i++ {
+		future, err := pool.SubmitFuture(ctx, exercise02workerpool.Task{ID: i})
+		if err != nil {
+			t.Fatalf("SubmitFuture returned %v", err)
+		}
+		futures = append(futures, future)
+	}
+	cancel()
+
+	wait, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	for i, future := range futures {
+		if _, err := future.Get(wait); err == nil || errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("future %d got %v, want the run's cancellation", i, err)
+		}
+	}
+	if _, err := futures[2].Get(wait); !errors.Is(err, exercise02workerpool.ErrPoolStopped) {
+		t.Errorf("the queued task's future got %v, want ErrPoolStopped", err)
+	}
+	for range pool.ResultChan {
+	}
+	if _, err := pool.SubmitFuture(context.Background(), exercise02workerpool.Task{}); !errors.Is(err, exercise02workerpool.ErrPoolStopped) {
+		t.Errorf("SubmitFuture after the run returned %v, want ErrPoolStopped", err)
+	}
+}
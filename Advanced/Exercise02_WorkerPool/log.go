@@ -0,0 +1,34 @@
+package exercise02workerpool
+
+import (
+	"context"  // Package for recognising cancellation, which is logged at a lower level.
+	"errors"   // Package for inspecting wrapped errors.
+	"log/slog" // Package for structured logging.
+)
+
+// discardLogger is used by every component that was not given a Logger.
+var discardLogger = slog.New(slog.DiscardHandler)
+
+// loggerOrDiscard returns logger, or a logger that discards everything if it is nil.
+func loggerOrDiscard(logger *slog.Logger) *slog.Logger {
+	if logger == nil {
+		return discardLogger
+	}
+	return logger
+}
+
+// errorLevel returns the level at which a failed attempt with err is logged:
+// panics are errors, cancellations (expected when a run is aborted) and
+// rejections by an open circuit breaker are debug events, and every other
+// failure is a warning.
+func errorLevel(err error) slog.Level {
+	var panicErr *PanicError
+	switch {
+	case errors.As(err, &panicErr):
+		return slog.LevelError
+	case errors.Is(err, context.Canceled), errors.Is(err, ErrCircuitOpen):
+		return slog.LevelDebug
+	default:
+		return slog.LevelWarn
+	}
+}
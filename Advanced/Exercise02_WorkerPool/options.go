@@ -0,0 +1,135 @@
+package exercise02workerpool
+
+import (
+	"log/slog" // Package for the structured logger used by the pool and its workers.
+	"time"     // Package for time-related settings such as the default task timeout.
+)
+
+// Option configures optional behaviour of a pool. Options are passed to NewPool
+// or NewTypedPool and apply to every worker the pool runs, including the ones
+// added later by Resize.
+type Option func(*poolOptions)
+
+// poolOptions holds the settings collected from the Options given to a pool.
+type poolOptions struct {
+	taskTimeout  time.Duration   // Default timeout for tasks that carry neither Deadline nor Timeout.
+	retry        RetryPolicy     // How failed tasks are retried; the zero value disables retries.
+	deadLetters  int             // Buffer size of DeadLetterChan; negative leaves it disabled.
+	logger       *slog.Logger    // Receives the events of the pool and its workers.
+	tracer       SpanExporter    // Receives the spans of every finished task; nil disables tracing.
+	ordered      int             // Size of the reorder window; zero delivers results in completion order.
+	limiter      *RateLimiter    // Paces the attempts handed to the workers; nil leaves them unpaced.
+	breaker      *CircuitBreaker // Shared by the workers to fail fast while the backend is failing; nil disables it.
+	wal          *WAL            // Records accepted and delivered tasks; nil disables it.
+	resultBuffer int             // Capacity of ResultChan; negative uses twice the number of workers.
+	queue        int             // Capacity of TaskChan; zero keeps it unbuffered.
+}
+
+// newPoolOptions applies opts over the defaults.
+func newPoolOptions(opts []Option) poolOptions {
+	o := poolOptions{deadLetters: -1, resultBuffer: -1, logger: discardLogger}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithTaskTimeout sets a pool-wide default timeout for tasks that set neither
+// Deadline nor Timeout themselves. A task that runs longer is abandoned and
+// comes back with an error wrapping ErrTaskTimeout. Zero disables the default.
+func WithTaskTimeout(d time.Duration) Option {
+	return func(o *poolOptions) { o.taskTimeout = d }
+}
+
+// WithRetryPolicy makes the pool retry failed tasks according to policy.
+// Retries wait for their backoff without occupying a worker, and a task only
+// reaches ResultChan once it has succeeded or the policy gives up on it.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(o *poolOptions) { o.retry = policy }
+}
+
+// WithDeadLetters gives the pool a DeadLetterChan with the given buffer size.
+// Tasks that failed permanently (their error was not retryable, or the retry
+// policy gave up) are sent there instead of to ResultChan, so ResultChan only
+// carries successes. The channel must be drained, like ResultChan: a full
+// dead-letter channel holds up the delivery of every other result.
+func WithDeadLetters(buffer int) Option {
+	return func(o *poolOptions) { o.deadLetters = max(buffer, 0) }
+}
+
+// WithLogger makes the pool and its workers log structured events to logger:
+// the run starting and finishing and resizes at Info, every task attempt at
+// Debug, failed attempts at Warn (Error for panics) and retries and permanent
+// failures with their attempt count. Events about a task carry its "task" ID,
+// and those logged by a worker its "worker" ID. By default nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *poolOptions) { o.logger = loggerOrDiscard(logger) }
+}
+
+// WithTracing records spans for every task (see Span) and hands them to
+// exporter once the task is finished: after its delivery to ResultChan or
+// DeadLetterChan. Tasks without a TraceID are given a random one.
+func WithTracing(exporter SpanExporter) Option {
+	return func(o *poolOptions) { o.tracer = exporter }
+}
+
+// WithOrderedResults makes the pool deliver results by ascending Task.ID,
+// starting from ID 0, as Producer numbers its tasks. Results that finish early
+// wait in a reorder buffer for the lower IDs. At most window tasks may be
+// accepted but not yet delivered: once the buffer is full the pool stops taking
+// tasks from TaskChan, holding back the producer, until the next ID is
+// delivered. A larger window keeps the workers busier when processing times
+// vary.
+//
+// The order is guaranteed as long as the IDs start from 0 without gaps and
+// fewer than window tasks with a higher ID are sent before each task. Otherwise
+// the pool cannot wait for a missing ID forever: once the window is full of
+// finished tasks, or the input is closed, it skips to the lowest ID it holds. A
+// task whose ID was skipped or already delivered, and a second task with an ID
+// still held back, are delivered as soon as they finish, out of order. Both
+// cases are logged as warnings.
+func WithOrderedResults(window int) Option {
+	return func(o *poolOptions) { o.ordered = max(window, 1) }
+}
+
+// WithRateLimit makes the pool start processing attempts, retries included, no
+// faster than limiter allows, e.g. to protect a rate-limited backend called by
+// the Processor. Tasks waiting for a token count as queued in Stats. The same
+// limiter may be shared with other pools or producers.
+func WithRateLimit(limiter *RateLimiter) Option {
+	return func(o *poolOptions) { o.limiter = limiter }
+}
+
+// WithCircuitBreaker makes every worker of the pool go through breaker: while it
+// is open, attempts fail fast with an error wrapping ErrCircuitOpen instead of
+// calling the Processor. Such attempts are retried like any other failure when a
+// retry policy is set. The breaker's state is reported by Stats.
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(o *poolOptions) { o.breaker = breaker }
+}
+
+// WithWAL makes the pool record in wal every task it accepts from TaskChan and
+// every task it delivers to ResultChan or DeadLetterChan. Tasks abandoned
+// because the run was cancelled are not recorded as delivered, so after an
+// interrupted or crashed run PendingTasks returns the tasks to send again.
+// Failing to write the log does not stop the pool; it is logged as an error.
+func WithWAL(wal *WAL) Option {
+	return func(o *poolOptions) { o.wal = wal }
+}
+
+// WithResultBuffer sets the capacity of ResultChan, which defaults to twice the
+// initial number of workers. A larger buffer lets the workers run further ahead
+// of a slow consumer; zero makes every delivery wait for the consumer.
+func WithResultBuffer(size int) Option {
+	return func(o *poolOptions) { o.resultBuffer = max(size, 0) }
+}
+
+// WithQueueCapacity gives TaskChan a buffer of the given size, so up to that
+// many tasks can be submitted while every worker is busy without waiting;
+// TrySubmit rejects a task with ErrQueueFull only once the queue is full. By
+// default TaskChan is unbuffered and every send waits for the pool to take the
+// task. Queued tasks are not accepted yet: Stats does not count them, WithWAL
+// does not record them, and they are dropped if the run is cancelled.
+func WithQueueCapacity(size int) Option {
+	return func(o *poolOptions) { o.queue = max(size, 0) }
+}
@@ -0,0 +1,97 @@
+package exercise02workerpool
+
+import (
+	"sort"        // Package for flushing the leftover tasks in ID order.
+	"sync/atomic" // Package for the count of waiting tasks read by the dispatcher.
+)
+
+// reorderBuffer holds finished tasks until their turn comes, so that results
+// leave the pool by ascending Task.ID. It expects consecutive IDs from 0 on, as
+// Producer generates them, and copes with the others this way:
+//
+//   - A missing ID is waited for until the window is full of finished tasks,
+//     i.e. the pool could not accept it anymore, and is then skipped: the
+//     lowest ID held is released next. The input ending skips it as well.
+//   - A task whose ID was already released or skipped (late), or is already
+//     held (duplicate), cannot take its place anymore: it is released as soon
+//     as it finishes, out of order.
+//
+// Only the collector goroutine calls its methods; the dispatcher only reads
+// Waiting.
+type reorderBuffer[In, Out any] struct {
+	next    int                        // ID of the next task to release.
+	window  int                        // How many tasks may be accepted but not delivered.
+	pending map[int]TypedTask[In, Out] // Finished tasks waiting for a lower ID.
+	waiting atomic.Int64               // len(pending), for the dispatcher.
+}
+
+// newReorderBuffer returns an empty buffer for the given window, expecting ID 0 first.
+func newReorderBuffer[In, Out any](window int) *reorderBuffer[In, Out] {
+	return &reorderBuffer[In, Out]{window: window, pending: make(map[int]TypedTask[In, Out])}
+}
+
+// add stores a finished task until its turn comes. It reports false, and keeps
+// nothing, if the task is late or a duplicate and must be released right away.
+func (b *reorderBuffer[In, Out]) add(task TypedTask[In, Out]) bool {
+	if _, held := b.pending[task.ID]; held || task.ID < b.next {
+		return false
+	}
+	b.pending[task.ID] = task
+	b.waiting.Store(int64(len(b.pending)))
+	return true
+}
+
+// pop removes and returns the next task in ID order, if it has finished.
+func (b *reorderBuffer[In, Out]) pop() (TypedTask[In, Out], bool) {
+	task, ok := b.pending[b.next]
+	if ok {
+		delete(b.pending, b.next)
+		b.waiting.Store(int64(len(b.pending)))
+		b.next++
+	}
+	return task, ok
+}
+
+// skip gives up on the next ID when the window is full of finished tasks
+// waiting for it: every slot is taken, so the pool cannot accept it anymore.
+// The lowest ID held is released next. It returns the ID given up on, and
+// false if the buffer is not stalled.
+func (b *reorderBuffer[In, Out]) skip() (int, bool) {
+	if len(b.pending) < b.window {
+		return 0, false
+	}
+	if _, ok := b.pending[b.next]; ok {
+		return 0, false
+	}
+	missing := b.next
+	first := true
+	for id := range b.pending {
+		if first || id < b.next {
+			b.next, first = id, false
+		}
+	}
+	return missing, true
+}
+
+// Waiting returns the number of finished tasks held. It is safe to call from
+// any goroutine.
+func (b *reorderBuffer[In, Out]) Waiting() int64 {
+	if b == nil {
+		return 0
+	}
+	return b.waiting.Load()
+}
+
+// flush removes and returns every task still buffered, in ID order. Tasks are
+// left behind when the input ended while they waited for a missing ID, or when
+// a cancelled run dropped an earlier task (e.g. one waiting for a retry).
+func (b *reorderBuffer[In, Out]) flush() []TypedTask[In, Out] {
+	tasks := make([]TypedTask[In, Out], 0, len(b.pending))
+	for _, task := range b.pending {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	clear(b.pending)
+	b.waiting.Store(0)
+	return tasks
+}
@@ -0,0 +1,38 @@
+package exercise02workerpool
+
+import (
+	"context"       // Package for the context handed to the Processor.
+	"fmt"           // Package for formatting the panic value.
+	"runtime/debug" // Package for capturing the stack trace of the panicking goroutine.
+)
+
+// PanicError is stored in Task.Err when the Processor panicked while handling
+// the task. The worker recovers from the panic and keeps serving, so one bad
+// task cannot bring down the whole program. Use errors.As to retrieve it.
+type PanicError struct {
+	Value any    // The value passed to panic.
+	Stack []byte // The stack trace of the goroutine at the time of the panic.
+}
+
+// Error returns a short description of the panic, without the stack trace.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("workerpool: processor panicked: %v", e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, so errors.Is and errors.As
+// can see through a panic(err).
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// safeProcess calls processor.Process and turns a panic into a *PanicError.
+func safeProcess[In, Out any](ctx context.Context, processor TypedProcessor[In, Out], task TypedTask[In, Out]) (result Out, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			var zero Out
+			result, err = zero, &PanicError{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return processor.Process(ctx, task)
+}
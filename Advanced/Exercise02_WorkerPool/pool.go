@@ -1,77 +1,323 @@
 package exercise02workerpool
 
-import "sync" // Package for synchronization primitives like WaitGroup.
+import (
+	"context"     // Package for cancellation signals shared by every worker of a run.
+	"errors"      // Package for defining the sentinel errors returned by the pool.
+	"fmt"         // Package for formatting validation errors.
+	"sync"        // Package for synchronization primitives like WaitGroup and Mutex.
+	"sync/atomic" // Package for the counter of tasks the pool has accepted but not finished.
+	"time"        // Package for time-related functions, used by the load counters.
+)
 
-// Pool manages the creation and orchestration of the worker goroutines
+// ErrPoolStopped is returned by Resize once the pool's run is shutting down,
+// i.e. after the task channel was closed and drained or the context was cancelled.
+var ErrPoolStopped = errors.New("workerpool: pool is shutting down")
+
+// TypedPool manages the creation and orchestration of the worker goroutines
 // and the communication channels between producers, workers, and consumers.
-type Pool struct {
-	TaskChan    chan Task // Channel for tasks to be sent to workers. Unbuffered for backpressure.
-	ResultChan  chan Task // Channel for results to be sent from workers to consumers. Buffered for throughput.
-	workerCount int       // The number of worker goroutines in this pool.
+// In is the type of the tasks' input data and Out the type of their results.
+type TypedPool[In, Out any] struct {
+	TaskChan   chan TypedTask[In, Out] // Channel for tasks to be sent to workers. Unbuffered for backpressure, unless set WithQueueCapacity.
+	ResultChan chan TypedTask[In, Out] // Channel for results to be sent from workers to consumers. Buffered for throughput.
+	// DeadLetterChan receives the tasks that failed permanently, with their error
+	// history in Task.Errors. It is nil unless the pool was created WithDeadLetters,
+	// and it is closed together with ResultChan.
+	DeadLetterChan chan TypedTask[In, Out]
+	processor      TypedProcessor[In, Out] // The processing step shared by every worker of this pool.
+	options        poolOptions             // Optional behaviour configured through Options.
+	input          poolInput               // Guards the closing of TaskChan by CloseInput and AddProducer.
+	futures        futureSet[In, Out]      // Futures of the tasks submitted with SubmitFuture and not delivered yet.
+
+	// Internal stages. The dispatcher moves tasks from TaskChan (and due retries)
+	// to the workers through work; workers hand finished tasks to the collector
+	// through completed; the collector retries them or delivers them to ResultChan.
+	work        chan TypedTask[In, Out] // Unbuffered channel the workers receive from.
+	completed   chan TypedTask[In, Out] // Unbuffered channel the workers send finished tasks to.
+	retries     chan TypedTask[In, Out] // Tasks whose backoff has elapsed, waiting to be dispatched again.
+	settled     chan struct{}           // Wakes the dispatcher when a task has been finished for good.
+	outstanding atomic.Int64            // Tasks accepted from TaskChan and not finished for good yet.
+	panics      atomic.Int64            // Number of attempts whose Processor panicked.
+	metrics     *poolMetrics            // Counters and histograms reported by Stats.
+
+	// Ordered results. The dispatcher takes a slot before accepting a task and
+	// the collector frees it once the task is delivered; both are nil unless
+	// the pool was created WithOrderedResults.
+	slots   chan struct{}           // Holds one element per task accepted and not yet delivered.
+	reorder *reorderBuffer[In, Out] // Finished tasks waiting for the lower IDs to be delivered.
+
+	mu          sync.Mutex              // Protects every field below; Resize may run concurrently with the workers.
+	workerCount int                     // The number of worker goroutines this pool should run.
+	workers     []*TypedWorker[In, Out] // The workers currently serving the pool (retired ones are removed).
+	live        []*TypedWorker[In, Out] // Every worker goroutine that has not returned yet, retired or not.
+	exitedLoad  poolLoad                // Counters accumulated by workers that have already returned.
+	exitedBusy  map[int]time.Duration   // Final busy time of every worker that has already returned, by ID.
+	nextID      int                     // The ID given to the next worker, so IDs are never reused.
+	ctx         context.Context         // The context of the current run, set by Start.
+	wg          sync.WaitGroup          // Counts running worker goroutines; completed is closed when it reaches zero.
+	started     bool                    // Whether Start has been called.
+	draining    bool                    // Whether a worker exited because the input ended or ctx was cancelled.
+	done        chan struct{}           // Closed together with ResultChan, once every worker has returned.
+	stopped     chan struct{}           // Closed when the dispatcher returns; nothing reads TaskChan after that.
+}
+
+// Pool is the pool type of the original prime-checking workload, working on Task.
+type Pool = TypedPool[int, any]
+
+// NewPool creates and returns a new Pool for Task values.
+// Every worker applies processor to the tasks it receives; a nil processor
+// selects PrimeProcessor, the original prime-checking workload.
+func NewPool(workerCount int, processor Processor, opts ...Option) *Pool {
+	if processor == nil {
+		processor = PrimeProcessor{}
+	}
+	return NewTypedPool(workerCount, processor, opts...)
 }
 
-// NewPool creates and returns a new Pool instance.
+// NewTypedPool creates and returns a new TypedPool instance.
 // It initializes the task and result channels with appropriate buffering.
-func NewPool(workerCount int) *Pool {
-	return &Pool{
-		// TaskChan is unbuffered (make(chan Task)). This means a sender (Producer)
+// Every worker applies processor, which must not be nil, to the tasks it receives;
+// opts enable optional behaviour such as a default task timeout.
+func NewTypedPool[In, Out any](workerCount int, processor TypedProcessor[In, Out], opts ...Option) *TypedPool[In, Out] {
+	options := newPoolOptions(opts)
+
+	var deadLetters chan TypedTask[In, Out]
+	if options.deadLetters >= 0 {
+		deadLetters = make(chan TypedTask[In, Out], options.deadLetters)
+	}
+
+	var slots chan struct{}
+	var reorder *reorderBuffer[In, Out]
+	if options.ordered > 0 {
+		slots = make(chan struct{}, options.ordered)
+		reorder = newReorderBuffer[In, Out](options.ordered)
+	}
+
+	resultBuffer := workerCount * 2
+	if options.resultBuffer >= 0 {
+		resultBuffer = options.resultBuffer // Set WithResultBuffer.
+	}
+
+	return &TypedPool[In, Out]{
+		// TaskChan is unbuffered (make(chan TypedTask[In, Out])). This means a sender (Producer)
 		// will block until a receiver (Worker) is ready to take the task.
 		// This provides a critical backpressure mechanism, preventing the producer
 		// from generating tasks faster than workers can consume them, thus
 		// avoiding unbounded memory usage for tasks awaiting processing.
-		TaskChan: make(chan Task),
+		// WithQueueCapacity gives it a bounded buffer instead, so callers that
+		// must not wait (see TrySubmit) can queue a few tasks ahead of the workers.
+		TaskChan: make(chan TypedTask[In, Out], options.queue),
 
-		// ResultChan is buffered (make(chan Task, workerCount*2)).
+		// ResultChan is buffered (make(chan TypedTask[In, Out], workerCount*2)).
 		// A buffered channel allows a sender (Worker) to send results without blocking
 		// immediately, as long as the buffer is not full. This increases throughput
 		// by decoupling workers from the consumer, allowing workers to continue
 		// processing new tasks while the consumer might be temporarily busy.
 		// The buffer size (workerCount*2) is a common heuristic, providing some
-		// slack without consuming excessive memory.
-		ResultChan: make(chan Task, workerCount*2),
+		// slack without consuming excessive memory; WithResultBuffer overrides it.
+		ResultChan: make(chan TypedTask[In, Out], resultBuffer),
+
+		DeadLetterChan: deadLetters, // Only created when dead letters are enabled.
+		input:          poolInput{closing: make(chan struct{})},
 
 		workerCount: workerCount, // Stores the number of workers this pool will manage.
+		processor:   processor,   // Stores the processing step handed to each worker.
+		options:     options,
+		work:        make(chan TypedTask[In, Out]),
+		completed:   make(chan TypedTask[In, Out]),
+		retries:     make(chan TypedTask[In, Out]),
+		settled:     make(chan struct{}, 1),
+		metrics:     newPoolMetrics(),
+		slots:       slots,
+		reorder:     reorder,
+		exitedBusy:  make(map[int]time.Duration),
+		done:        make(chan struct{}),
+		stopped:     make(chan struct{}),
 	}
 }
 
 // Start launches all worker goroutines and manages the graceful closing of the ResultChan.
 // This method sets up the core concurrency of the worker pool.
-func (p *Pool) Start() {
-	// A WaitGroup is used to wait for all worker goroutines to complete their work.
-	// It's local to the Pool.Start method because it's only concerned with the workers
-	// managed by this specific pool instance.
-	var wg sync.WaitGroup
+// Cancelling ctx aborts the run: idle workers exit at once, busy workers abandon
+// their current task (reporting ctx.Err() in Task.Err), tasks waiting for a retry
+// are dropped, and ResultChan is still closed once every worker has returned.
+func (p *TypedPool[In, Out]) Start(ctx context.Context) {
+	p.mu.Lock()
+	p.ctx = ctx
+	p.started = true
+	// Launch the configured number of worker goroutines.
+	p.spawn(p.workerCount)
+	p.options.logger.InfoContext(ctx, "pool started", "workers", p.workerCount)
+	p.mu.Unlock()
+
+	// The dispatcher feeds the workers; it closes the work channel once TaskChan
+	// is closed and every accepted task is finished, which lets the workers exit.
+	go p.dispatch(ctx)
+
+	// Launch a separate goroutine to manage the closing of the completed channel.
+	go func() {
+		// wg.Wait() blocks this goroutine until the WaitGroup counter becomes zero.
+		// This means it waits until ALL worker goroutines launched by this pool,
+		// including those added later by Resize, have completed their execution.
+		// Workers never block on a cancelled context, so this also returns promptly
+		// when the run is aborted and no goroutine is leaked.
+		p.wg.Wait()
+		// Once all workers are done, nothing else will be sent to the collector.
+		close(p.completed)
+	}()
+
+	// The collector delivers results. After the completed channel is closed and
+	// drained it closes the ResultChan. This is crucial for a graceful shutdown,
+	// as the consumer's receive loop on ResultChan will only terminate when
+	// ResultChan is closed.
+	go p.collect(ctx)
+}
+
+// Resize changes the number of workers to n while the pool is running.
+// New workers start serving immediately; retired workers finish and deliver the
+// task they are working on before exiting. Before Start, Resize only changes the
+// number of workers Start will launch. n must be at least 1, so that the input is
+// always drained, and ErrPoolStopped is returned once the run is shutting down.
+func (p *TypedPool[In, Out]) Resize(n int) error {
+	if n < 1 {
+		return fmt.Errorf("workerpool: worker count must be at least 1, got %d", n)
+	}
 
-	// Loop to launch the specified number of worker goroutines.
-	for i := 0; i < p.workerCount; i++ {
-		wg.Add(1) // Increment the WaitGroup counter for each worker about to be launched.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.started {
+		p.workerCount = n
+		return nil
+	}
+	if p.draining {
+		return ErrPoolStopped
+	}
+
+	if n > len(p.workers) {
+		p.spawn(n - len(p.workers))
+	}
+	// Retire the most recently added workers. They are removed from the active
+	// list right away, so a second Resize never counts them again.
+	for len(p.workers) > n {
+		last := len(p.workers) - 1
+		p.workers[last].Stop()
+		p.workers = p.workers[:last]
+	}
+	p.options.logger.InfoContext(p.ctx, "pool resized", "from", p.workerCount, "to", n)
+	p.workerCount = n
+	return nil
+}
+
+// Size returns the number of workers the pool is configured to run, as set by
+// NewPool or the last successful Resize. Retired workers that are still
+// finishing a task are not counted.
+func (p *TypedPool[In, Out]) Size() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.workerCount
+}
+
+// Panics returns the number of task attempts whose Processor panicked so far.
+// Each panic was recovered by the worker and reported as a *PanicError in Task.Err.
+func (p *TypedPool[In, Out]) Panics() int64 {
+	return p.panics.Load()
+}
+
+// spawn launches n additional workers. The caller must hold p.mu.
+//
+// Adding to the WaitGroup is only safe while its counter is above zero (or
+// before Wait is called). Both hold here: spawn runs either from Start, before
+// the closing goroutine calls Wait, or from Resize while the pool is not
+// draining, which means at least one worker that was not retired is still running.
+func (p *TypedPool[In, Out]) spawn(n int) {
+	for i := 0; i < n; i++ {
+		p.wg.Add(1) // Increment the WaitGroup counter for each worker about to be launched.
 
 		// Create a new Worker instance for each goroutine.
-		// Each worker receives its unique ID, the shared TaskChan, and the shared ResultChan.
-		worker := NewWorker(i, p.TaskChan, p.ResultChan)
+		// Each worker receives its unique ID, the shared Processor, and the pool's
+		// internal work and completed channels.
+		worker := NewTypedWorker(p.nextID, p.processor, p.work, p.completed)
+		worker.Timeout = p.options.taskTimeout
+		worker.Logger = p.options.logger
+		worker.Breaker = p.options.breaker
+		p.nextID++
+		p.workers = append(p.workers, worker)
+		p.live = append(p.live, worker)
 
 		// Launch the worker's processing loop in a new goroutine.
 		go func() {
 			// Defer wg.Done() ensures that the WaitGroup counter is decremented
 			// when this goroutine finishes, regardless of how it exits (e.g., normally, panics).
-			defer wg.Done()
+			defer p.wg.Done()
 			// Call the worker's Start method. This method will block and process tasks
-			// until the TaskChan is closed by the producer and drained.
-			worker.Start() // Start each worker in its own goroutine
+			// until the dispatcher closes the work channel, ctx is cancelled, or the
+			// worker is retired by Resize.
+			worker.Start(p.ctx) // Start each worker in its own goroutine
+			p.exited(worker)
 		}()
 	}
+}
 
-	// Launch a separate goroutine to manage the closing of the ResultChan.
-	// This is crucial for a graceful shutdown, as the consumer's 'for range' loop
-	// on ResultChan will only terminate when ResultChan is closed.
-	go func() {
-		// wg.Wait() blocks this goroutine until the WaitGroup counter becomes zero.
-		// This means it waits until ALL worker goroutines launched by this pool
-		// have completed their execution (signaled by defer wg.Done()).
-		wg.Wait()
-		// Once all workers are done, close the ResultChan.
-		// This signals to the consumer (and any other goroutines reading from ResultChan)
-		// that no more results will be sent, allowing their 'for range' loops to exit gracefully.
-		close(p.ResultChan)
-	}()
+// exited records that worker has returned from Start.
+// A worker that was not retired only returns once the input is exhausted or the
+// run is cancelled, so from then on the pool is draining and must not grow.
+func (p *TypedPool[In, Out]) exited(worker *TypedWorker[In, Out]) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Fold the worker's counters into the pool totals so they survive its exit.
+	load := workerLoad(worker)
+	p.exitedLoad.add(load)
+	p.exitedBusy[worker.ID] = load.busy
+	p.live = removeWorker(p.live, worker)
+
+	if worker.stopped() {
+		return // Retired by Resize; already removed from p.workers.
+	}
+	p.draining = true
+	p.workers = removeWorker(p.workers, worker)
+}
+
+// removeWorker returns workers without worker.
+func removeWorker[In, Out any](workers []*TypedWorker[In, Out], worker *TypedWorker[In, Out]) []*TypedWorker[In, Out] {
+	for i, w := range workers {
+		if w == worker {
+			return append(workers[:i], workers[i+1:]...)
+		}
+	}
+	return workers
+}
+
+// poolLoad is a snapshot of the cumulative counters used to judge how loaded the pool is.
+type poolLoad struct {
+	busy      time.Duration // Total time workers spent processing tasks.
+	latency   time.Duration // Total time from a worker taking a task to delivering its result.
+	delivered int64         // Number of results delivered.
+}
+
+// add accumulates other into l.
+func (l *poolLoad) add(other poolLoad) {
+	l.busy += other.busy
+	l.latency += other.latency
+	l.delivered += other.delivered
+}
+
+// workerLoad reads the cumulative counters of a single worker.
+func workerLoad[In, Out any](w *TypedWorker[In, Out]) poolLoad {
+	return poolLoad{
+		busy:      w.BusyTime(),
+		latency:   time.Duration(w.latencyNanos.Load()),
+		delivered: w.delivered.Load(),
+	}
+}
+
+// load returns the cumulative counters of every worker the pool has ever run.
+func (p *TypedPool[In, Out]) load() poolLoad {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	total := p.exitedLoad
+	for _, w := range p.live {
+		total.add(workerLoad(w))
+	}
+	return total
 }
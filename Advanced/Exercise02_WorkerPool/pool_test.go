@@ -0,0 +1,582 @@
+package exercise02workerpool_test
+
+import (
+	"bytes"         // Used to capture the pool's logs.
+	"context"       // Used to cancel runs from the tests.
+	"encoding/json" // Used to decode the captured JSON logs.
+	"errors"        // Used to compare task errors against context errors.
+	"log/slog"      // Used to give the pool a logger.
+	"math/rand"     // Used to shuffle the IDs sent to an ordered pool.
+	"testing"       // The testing package is required for tests.
+	"time"          // Used for task complexities and test timeouts.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// collect drains resultChan until it is closed, failing the test if that takes too long.
+func collect(t *testing.T, resultChan <-chan exercise02workerpool.Task) []exercise02workerpool.Task {
+	t.Helper()
+	var results []exercise02workerpool.Task
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case task, ok := <-resultChan:
+			if !ok {
+				return results
+			}
+			results = append(results, task)
+		case <-timeout:
+			t.Fatalf("ResultChan was not closed; received %d results", len(results))
+		}
+	}
+}
+
+// TestPoolProcessesAllTasks checks the normal run: every task comes back exactly once.
+func TestPoolProcessesAllTasks(t *testing.T) {
+	pool := exercise02workerpool.NewPool(4, nil)
+	pool.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 20; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i, Data: i}
+		}
+		close(pool.TaskChan)
+	}()
+
+	results := collect(t, pool.ResultChan)
+	if len(results) != 20 {
+		t.Fatalf("got %d results, want 20", len(results))
+	}
+	seen := make(map[int]bool)
+	for _, task := range results {
+		if task.Err != nil {
+			t.Errorf("task %d: unexpected error %v", task.ID, task.Err)
+		}
+		seen[task.ID] = true
+	}
+	if len(seen) != 20 {
+		t.Errorf("got %d distinct task IDs, want 20", len(seen))
+	}
+}
+
+// TestPoolCancellation checks that cancelling the context stops the producer,
+// abandons in-flight tasks with ctx.Err() and still closes ResultChan.
+func TestPoolCancellation(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	pool := exercise02workerpool.NewPool(2, nil)
+	pool.Start(ctx)
+
+	producer := exercise02workerpool.NewProducer(1000, pool.TaskChan)
+	producerDone := make(chan struct{})
+	go func() {
+		defer close(producerDone)
+		producer.Start(ctx)
+	}()
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	results := collect(t, pool.ResultChan)
+	select {
+	case <-producerDone:
+	case <-time.After(time.Second):
+		t.Fatal("producer did not stop after cancellation")
+	}
+
+	if len(results) >= 1000 {
+		t.Fatalf("got %d results, expected the run to be cut short", len(results))
+	}
+	for _, task := range results {
+		if task.Err != nil && !errors.Is(task.Err, context.Canceled) {
+			t.Errorf("task %d: got error %v, want context.Canceled", task.ID, task.Err)
+		}
+	}
+}
+
+// TestPoolCustomProcessor checks that a user-supplied Processor replaces the
+// prime check and that its error lands in Task.Err.
+func TestPoolCustomProcessor(t *testing.T) {
+	errOdd := errors.New("odd input")
+	double := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		if task.Data%2 != 0 {
+			return nil, errOdd
+		}
+		return task.Data * 2, nil
+	})
+
+	pool := exercise02workerpool.NewPool(3, double)
+	pool.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i, Data: i}
+		}
+		close(pool.TaskChan)
+	}()
+
+	for _, task := range collect(t, pool.ResultChan) {
+		if task.Data%2 != 0 {
+			if !errors.Is(task.Err, errOdd) {
+				t.Errorf("task %d: got error %v, want %v", task.ID, task.Err, errOdd)
+			}
+			continue
+		}
+		if task.Err != nil || task.Result != task.Data*2 {
+			t.Errorf("task %d: got (%v, %v), want (%d, nil)", task.ID, task.Result, task.Err, task.Data*2)
+		}
+	}
+}
+
+// TestTypedPool checks a pool with structured input and typed results, and that
+// it keeps the original channel layout (unbuffered tasks, workerCount*2 results)
+// unless WithResultBuffer says otherwise.
+func TestTypedPool(t *testing.T) {
+	type word struct{ Text string }
+	length := exercise02workerpool.TypedProcessorFunc[word, int](func(ctx context.Context, task exercise02workerpool.TypedTask[word, int]) (int, error) {
+		return len(task.Data.Text), nil
+	})
+
+	pool := exercise02workerpool.NewTypedPool(3, length)
+	if cap(pool.TaskChan) != 0 || cap(pool.ResultChan) != 6 {
+		t.Fatalf("got channel capacities (%d, %d), want (0, 6)", cap(pool.TaskChan), cap(pool.ResultChan))
+	}
+	if buffered := exercise02workerpool.NewTypedPool(3, length, exercise02workerpool.WithResultBuffer(50)); cap(buffered.ResultChan) != 50 {
+		t.Errorf("WithResultBuffer(50) gave ResultChan a capacity of %d", cap(buffered.ResultChan))
+	}
+	pool.Start(context.Background())
+
+	words := []string{"a", "go", "pool", "worker"}
+	producer := exercise02workerpool.NewTypedProducer(len(words), pool.TaskChan, func(id int) exercise02workerpool.TypedTask[word, int] {
+		return exercise02workerpool.TypedTask[word, int]{ID: id, Data: word{Text: words[id]}}
+	})
+	go producer.Start(context.Background())
+
+	total := 0
+	consumer := exercise02workerpool.NewTypedConsumer(pool.ResultChan, func(task exercise02workerpool.TypedTask[word, int]) {
+		total += task.Result // Result is an int: no type assertion needed.
+	})
+	if n, err := consumer.Start(context.Background()); n != len(words) || err != nil {
+		t.Fatalf("consumer received %d tasks (error %v), want %d", n, err, len(words))
+	}
+	if total != 13 {
+		t.Errorf("got total length %d, want 13", total)
+	}
+}
+
+// TestPoolResize grows and shrinks a running pool and checks that no task is
+// lost and that ResultChan is still closed at the end.
+func TestPoolResize(t *testing.T) {
+	slow := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		time.Sleep(time.Millisecond)
+		return task.Data, nil
+	})
+
+	pool := exercise02workerpool.NewPool(2, slow)
+	pool.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 200; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i, Data: i}
+			switch i {
+			case 50:
+				if err := pool.Resize(8); err != nil {
+					t.Errorf("Resize(8): %v", err)
+				}
+				if n := pool.Size(); n != 8 {
+					t.Errorf("Size() = %d after Resize(8)", n)
+				}
+			case 120:
+				if err := pool.Resize(1); err != nil {
+					t.Errorf("Resize(1): %v", err)
+				}
+				if n := pool.Size(); n != 1 {
+					t.Errorf("Size() = %d after Resize(1)", n)
+				}
+			}
+		}
+		close(pool.TaskChan)
+	}()
+
+	if results := collect(t, pool.ResultChan); len(results) != 200 {
+		t.Fatalf("got %d results, want 200", len(results))
+	}
+	if err := pool.Resize(4); !errors.Is(err, exercise02workerpool.ErrPoolStopped) {
+		t.Errorf("Resize after the run: got %v, want ErrPoolStopped", err)
+	}
+	if err := pool.Resize(0); err == nil {
+		t.Error("Resize(0): expected an error")
+	}
+}
+
+// TestPoolTaskTimeouts checks per-task timeouts and the pool-wide default,
+// including a Processor that ignores its context.
+func TestPoolTaskTimeouts(t *testing.T) {
+	stubborn := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		time.Sleep(task.Complexity) // Deliberately ignores ctx.
+		return true, nil
+	})
+
+	pool := exercise02workerpool.NewPool(4, stubborn, exercise02workerpool.WithTaskTimeout(20*time.Millisecond))
+	pool.Start(context.Background())
+
+	go func() {
+		// Task 0 is quick, task 1 exceeds the pool default, task 2 has its own
+		// longer Timeout, and task 3's Deadline has already passed.
+		pool.TaskChan <- exercise02workerpool.Task{ID: 0, Complexity: time.Millisecond}
+		pool.TaskChan <- exercise02workerpool.Task{ID: 1, Complexity: 200 * time.Millisecond}
+		pool.TaskChan <- exercise02workerpool.Task{ID: 2, Complexity: 40 * time.Millisecond, Timeout: time.Second}
+		pool.TaskChan <- exercise02workerpool.Task{ID: 3, Deadline: time.Now().Add(-time.Second)}
+		close(pool.TaskChan)
+	}()
+
+	wantTimeout := map[int]bool{0: false, 1: true, 2: false, 3: true}
+	for _, task := range collect(t, pool.ResultChan) {
+		if got := errors.Is(task.Err, exercise02workerpool.ErrTaskTimeout); got != wantTimeout[task.ID] {
+			t.Errorf("task %d: got error %v, want timeout = %v", task.ID, task.Err, wantTimeout[task.ID])
+		}
+	}
+}
+
+// TestPoolRetries checks that failed tasks are retried up to MaxAttempts, that
+// Attempts is counted, and that a task waiting for its backoff does not hold
+// up the only worker.
+func TestPoolRetries(t *testing.T) {
+	errFlaky := errors.New("flaky backend")
+	flaky := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		switch {
+		case task.ID == 0 && task.Attempts < 3: // Succeeds on the third attempt.
+			return nil, errFlaky
+		case task.ID == 1: // Never succeeds.
+			return nil, errFlaky
+		}
+		return task.ID, nil
+	})
+
+	pool := exercise02workerpool.NewPool(1, flaky, exercise02workerpool.WithRetryPolicy(exercise02workerpool.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 30 * time.Millisecond,
+		Jitter:         0.2,
+	}))
+	pool.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i}
+		}
+		close(pool.TaskChan)
+	}()
+
+	results := collect(t, pool.ResultChan)
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3", len(results))
+	}
+	// Task 2 never fails, so it must overtake the tasks that are backing off.
+	if results[0].ID != 2 {
+		t.Errorf("first result is task %d, want task 2", results[0].ID)
+	}
+	for _, task := range results {
+		switch task.ID {
+		case 0:
+			if task.Err != nil || task.Attempts != 3 {
+				t.Errorf("task 0: got (err %v, attempts %d), want (nil, 3)", task.Err, task.Attempts)
+			}
+		case 1:
+			if !errors.Is(task.Err, errFlaky) || task.Attempts != 3 {
+				t.Errorf("task 1: got (err %v, attempts %d), want (%v, 3)", task.Err, task.Attempts, errFlaky)
+			}
+		case 2:
+			if task.Err != nil || task.Attempts != 1 {
+				t.Errorf("task 2: got (err %v, attempts %d), want (nil, 1)", task.Err, task.Attempts)
+			}
+		}
+	}
+}
+
+// TestRetryPolicyBackoff checks exponential growth, the MaxBackoff cap and jitter bounds.
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := exercise02workerpool.RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	want := []time.Duration{10, 20, 40, 50, 50}
+	for i, w := range want {
+		if got := policy.Backoff(i + 1); got != w*time.Millisecond {
+			t.Errorf("Backoff(%d) = %v, want %v", i+1, got, w*time.Millisecond)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := policy.Backoff(2); got < 10*time.Millisecond || got > 20*time.Millisecond {
+			t.Fatalf("Backoff(2) with jitter = %v, want within [10ms, 20ms]", got)
+		}
+	}
+}
+
+// TestPoolDeadLetters checks that permanently failed tasks go to DeadLetterChan
+// with their error history, while ResultChan only carries successes.
+func TestPoolDeadLetters(t *testing.T) {
+	errOdd := errors.New("odd input")
+	oddFails := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		if task.Data%2 != 0 {
+			return nil, errOdd
+		}
+		return true, nil
+	})
+
+	pool := exercise02workerpool.NewPool(2, oddFails,
+		exercise02workerpool.WithRetryPolicy(exercise02workerpool.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}),
+		exercise02workerpool.WithDeadLetters(4))
+	pool.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i, Data: i}
+		}
+		close(pool.TaskChan)
+	}()
+
+	deadLetters := make(chan []exercise02workerpool.Task)
+	go func() {
+		var tasks []exercise02workerpool.Task
+		for task := range pool.DeadLetterChan {
+			tasks = append(tasks, task)
+		}
+		deadLetters <- tasks
+	}()
+
+	results := collect(t, pool.ResultChan)
+	if len(results) != 5 {
+		t.Errorf("got %d results, want 5", len(results))
+	}
+	for _, task := range results {
+		if task.Err != nil {
+			t.Errorf("task %d: failed task delivered to ResultChan: %v", task.ID, task.Err)
+		}
+	}
+
+	failed := <-deadLetters
+	if len(failed) != 5 {
+		t.Fatalf("got %d dead letters, want 5", len(failed))
+	}
+	for _, task := range failed {
+		if task.Attempts != 2 || len(task.Errors) != 2 || !errors.Is(task.Err, errOdd) {
+			t.Errorf("dead letter %d: got (attempts %d, errors %v, err %v)", task.ID, task.Attempts, task.Errors, task.Err)
+		}
+		if fresh := task.Reset(); fresh.Attempts != 0 || fresh.Errors != nil || fresh.Err != nil || fresh.Data != task.Data {
+			t.Errorf("dead letter %d: Reset kept outcome fields: %+v", task.ID, fresh)
+		}
+	}
+}
+
+// TestPoolPanicIsolation checks that a panicking Processor fails only its own
+// task, with a *PanicError carrying the value and stack, and that the pool
+// keeps serving and counts the panics.
+func TestPoolPanicIsolation(t *testing.T) {
+	fragile := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		if task.Data%3 == 0 {
+			panic("cannot handle multiples of three")
+		}
+		return task.Data, nil
+	})
+
+	pool := exercise02workerpool.NewPool(2, fragile)
+	pool.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 9; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i, Data: i}
+		}
+		close(pool.TaskChan)
+	}()
+
+	results := collect(t, pool.ResultChan)
+	if len(results) != 9 {
+		t.Fatalf("got %d results, want 9", len(results))
+	}
+	for _, task := range results {
+		var panicErr *exercise02workerpool.PanicError
+		panicked := errors.As(task.Err, &panicErr)
+		if panicked != (task.Data%3 == 0) {
+			t.Errorf("task %d: got error %v", task.ID, task.Err)
+		}
+		if panicked && (panicErr.Value != "cannot handle multiples of three" || len(panicErr.Stack) == 0) {
+			t.Errorf("task %d: got panic value %v with %d bytes of stack", task.ID, panicErr.Value, len(panicErr.Stack))
+		}
+	}
+	if got := pool.Panics(); got != 3 {
+		t.Errorf("Panics() = %d, want 3", got)
+	}
+}
+
+// TestPoolLogging checks that a pool created WithLogger logs every attempt with
+// the task and worker IDs, and the retry of a failed attempt.
+func TestPoolLogging(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	proc := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		if task.ID == 1 && task.Attempts == 1 {
+			return nil, errors.New("flaky")
+		}
+		return nil, nil
+	})
+	pool := exercise02workerpool.NewPool(2, proc,
+		exercise02workerpool.WithLogger(logger),
+		exercise02workerpool.WithRetryPolicy(exercise02workerpool.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	pool.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i}
+		}
+		close(pool.TaskChan)
+	}()
+	collect(t, pool.ResultChan)
+
+	counts := make(map[string]int)
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var event struct {
+			Msg     string
+			Task    *int
+			Worker  *int
+			Attempt int
+			Error   string
+		}
+		if err := json.Unmarshal(line, &event); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		counts[event.Msg]++
+
+		switch event.Msg {
+		case "task processed", "task attempt failed":
+			if event.Task == nil || event.Worker == nil || event.Attempt == 0 {
+				t.Errorf("%s event without task, worker or attempt: %s", event.Msg, line)
+			}
+		case "task retry scheduled":
+			if event.Task == nil || *event.Task != 1 || event.Error != "flaky" {
+				t.Errorf("unexpected retry event: %s", line)
+			}
+		}
+	}
+
+	want := map[string]int{"pool started": 1, "task processed": 3, "task attempt failed": 1, "task retry scheduled": 1, "pool finished": 1}
+	for msg, n := range want {
+		if counts[msg] != n {
+			t.Errorf("got %d %q events, want %d", counts[msg], msg, n)
+		}
+	}
+}
+
+// TestPoolOrderedResults checks that WithOrderedResults delivers results by
+// ascending ID despite IDs sent out of order within the window, varying
+// processing times and retries, and that no more than the window of tasks is
+// ever pending.
+func TestPoolOrderedResults(t *testing.T) {
+	const window = 4
+	proc := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		time.Sleep(time.Duration(task.Data%5) * time.Millisecond)
+		if task.ID%7 == 0 && task.Attempts == 1 {
+			return nil, errors.New("flaky")
+		}
+		return task.Data, nil
+	})
+	pool := exercise02workerpool.NewPool(3, proc,
+		exercise02workerpool.WithOrderedResults(window),
+		exercise02workerpool.WithRetryPolicy(exercise02workerpool.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	pool.Start(context.Background())
+
+	// The IDs are shuffled within consecutive chunks of window tasks, so fewer
+	// than window higher IDs are ever sent before a lower one.
+	random := rand.New(rand.NewSource(1))
+	go func() {
+		for chunk := 0; chunk < 40; chunk += window {
+			for _, j := range random.Perm(window) {
+				i := chunk + j
+				pool.TaskChan <- exercise02workerpool.Task{ID: i, Data: (i * 7) % 11}
+			}
+		}
+		close(pool.TaskChan)
+	}()
+
+	stop := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if pending := pool.Stats().Pending; pending > window {
+					t.Errorf("%d tasks pending, want at most %d", pending, window)
+				}
+			}
+		}
+	}()
+
+	results := collect(t, pool.ResultChan)
+	close(stop)
+	<-polled
+
+	if len(results) != 40 {
+		t.Fatalf("got %d results, want 40", len(results))
+	}
+	for i, task := range results {
+		if task.ID != i {
+			t.Fatalf("result %d is task %d, want results in ID order", i, task.ID)
+		}
+		if task.Err != nil {
+			t.Errorf("task %d: unexpected error %v", task.ID, task.Err)
+		}
+	}
+}
+
+// TestPoolOrderedResultsOutOfOrder checks how WithOrderedResults handles IDs
+// that do not follow each other: a missing ID is skipped once the window is
+// full, and late or duplicate IDs are delivered as soon as they finish.
+func TestPoolOrderedResultsOutOfOrder(t *testing.T) {
+	proc := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		return task.Data, nil
+	})
+	pool := exercise02workerpool.NewPool(2, proc, exercise02workerpool.WithOrderedResults(2))
+	pool.Start(context.Background())
+
+	go func() {
+		// 5 and 1 fill the window while 0 is missing, so 1 is released; 3 and 5
+		// fill it again, so 3 is. 0 arrives late and the second 5 is a duplicate
+		// of a held ID: both are released at once. The input ending releases 5.
+		for _, task := range []exercise02workerpool.Task{{ID: 5, Data: 50}, {ID: 1}, {ID: 3}, {ID: 0}, {ID: 5, Data: 51}} {
+			pool.TaskChan <- task
+		}
+		close(pool.TaskChan)
+	}()
+
+	results := collect(t, pool.ResultChan)
+	want := []exercise02workerpool.Task{{ID: 1}, {ID: 3}, {ID: 0}, {ID: 5, Data: 51}, {ID: 5, Data: 50}}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for i, task := range results {
+		if task.ID != want[i].ID || task.Data != want[i].Data {
+			t.Errorf("result %d is task %d (data %d), want task %d (data %d)", i, task.ID, task.Data, want[i].ID, want[i].Data)
+		}
+	}
+
+	// IDs shuffled within the window come out ascending.
+	pool = exercise02workerpool.NewPool(2, proc, exercise02workerpool.WithOrderedResults(4))
+	pool.Start(context.Background())
+	go func() {
+		for _, id := range []int{3, 1, 2, 0, 5, 4} {
+			pool.TaskChan <- exercise02workerpool.Task{ID: id}
+		}
+		close(pool.TaskChan)
+	}()
+	results = collect(t, pool.ResultChan)
+	if len(results) != 6 {
+		t.Fatalf("got %d results, want 6", len(results))
+	}
+	for i, task := range results {
+		if task.ID != i {
+			t.Errorf("result %d is task %d, want results in ID order", i, task.ID)
+		}
+	}
+}
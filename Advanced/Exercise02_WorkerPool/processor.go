@@ -0,0 +1,50 @@
+package exercise02workerpool
+
+import (
+	"context" // Package for cancellation signals passed to every processing call.
+	"time"    // Package for time-related functions, used to simulate processing time.
+)
+
+// TypedProcessor performs the actual work for a task on behalf of a TypedWorker.
+// The returned value is stored in Result and the returned error in Err.
+// Implementations should honour ctx and return promptly (typically with ctx.Err())
+// once it is cancelled, so that an aborted run does not wait for them.
+type TypedProcessor[In, Out any] interface {
+	Process(ctx context.Context, task TypedTask[In, Out]) (Out, error)
+}
+
+// TypedProcessorFunc is an adapter that allows an ordinary function to be used as a TypedProcessor.
+type TypedProcessorFunc[In, Out any] func(ctx context.Context, task TypedTask[In, Out]) (Out, error)
+
+// Process calls f(ctx, task).
+func (f TypedProcessorFunc[In, Out]) Process(ctx context.Context, task TypedTask[In, Out]) (Out, error) {
+	return f(ctx, task)
+}
+
+// Processor is the processing step of the original pool, working on Task.
+type Processor = TypedProcessor[int, any]
+
+// ProcessorFunc is the function adapter for Processor.
+type ProcessorFunc = TypedProcessorFunc[int, any]
+
+// PrimeProcessor is the default Processor used by the pool.
+// It simulates work by waiting for Task.Complexity and then reports whether
+// Task.Data is a prime number.
+type PrimeProcessor struct{}
+
+// Process waits for the task's complexity and returns isPrime(task.Data).
+// If ctx is cancelled during the wait, the task is abandoned and ctx.Err() is returned.
+func (PrimeProcessor) Process(ctx context.Context, task Task) (any, error) {
+	// Simulate Processing time based on the task's defined complexity.
+	// A timer is used instead of time.Sleep so that the wait can be interrupted.
+	timer := time.NewTimer(task.Complexity)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		// Perform the CPU-intensive calculation for the task.
+		return isPrime(task.Data), nil
+	case <-ctx.Done():
+		return nil, ctx.Err() // The task was abandoned; report why.
+	}
+}
@@ -1,59 +1,282 @@
 package exercise02workerpool
 
 import (
-	"math/rand" // Package for generating pseudo-random numbers.
-	"time"      // Package for time-related functions, used for seeding the random number generator.
+	"context"     // Package for cancellation signals that stop task generation early.
+	"log/slog"    // Package for the structured events logged for every task sent.
+	"math/rand"   // Package for generating pseudo-random numbers.
+	"sync/atomic" // Package for the blocked-time counter read while the producer runs.
+	"time"        // Package for time-related functions, used for seeding the random number generator.
 )
 
-// Producer is responsible for generating tasks and sending them to the task channel.
-type Producer struct {
-	TaskCount    int         // The total number of tasks this producer will generate.
-	TaskChan     chan<- Task // A send-only channel where the producer sends newly created tasks.
-	RandomNumber *rand.Rand  // A source of pseudo-random numbers for generating task data and complexity.
+// BlockReporter is implemented by producers that measure how long they have been
+// blocked waiting for a worker to accept a task. A producer that is blocked most
+// of the time is a sign that the pool has too few workers.
+type BlockReporter interface {
+	BlockedTime() time.Duration
 }
 
-// NewProducer creates and returns a new Producer instance.
-// It initializes the producer with the total number of tasks to create
-// and the channel through which it will send these tasks.
-func NewProducer(taskCount int, taskChan chan<- Task) *Producer {
-	return &Producer{
+// TypedProducer is responsible for generating tasks and sending them to the task channel.
+// The tasks themselves are built by the Generate function, so any payload type can be produced.
+type TypedProducer[In, Out any] struct {
+	TaskCount int                             // The total number of tasks this producer will generate.
+	FirstID   int                             // The ID of the first task; the others follow sequentially.
+	TaskChan  chan<- TypedTask[In, Out]       // A send-only channel where the producer sends newly created tasks.
+	Input     *TypedPoolInput[In, Out]        // If set, the tasks are submitted through Input instead of TaskChan (see AddProducer).
+	Generate  func(id int) TypedTask[In, Out] // Builds the task with the given sequential ID.
+	Logger    *slog.Logger                    // Receives an event for every task sent. Nil logs nothing.
+	Limiter   *RateLimiter                    // Paces the tasks sent. Nil sends them as fast as they are accepted.
+
+	blockedNanos atomic.Int64 // Total time spent waiting for TaskChan to accept a task.
+}
+
+// NewTypedProducer creates and returns a new TypedProducer instance.
+// It initializes the producer with the total number of tasks to create, the channel
+// through which it will send these tasks and the function that builds each task.
+func NewTypedProducer[In, Out any](taskCount int, taskChan chan<- TypedTask[In, Out], generate func(id int) TypedTask[In, Out]) *TypedProducer[In, Out] {
+	return &TypedProducer[In, Out]{
 		TaskCount: taskCount, // Sets the total number of tasks to be generated.
 		TaskChan:  taskChan,  // Assigns the channel to send tasks.
-		// Initializes a new pseudo-random number generator.
-		// rand.NewSource(time.Now().UnixNano()) seeds the generator with the current nanosecond timestamp,
-		// ensuring different sequences of random numbers on each program run.
-		RandomNumber: rand.New(rand.NewSource(time.Now().UnixNano())),
+		Generate:  generate,  // Assigns the task factory.
 	}
 }
 
 // Start begins the task generation process.
-// This method is designed to be run in its own goroutine.
-func (p *Producer) Start() {
-	// Loop 'TaskCount' times to generate the specified number of tasks.
-	for i := 0; i < p.TaskCount; i++ {
-		// Create a new Task instance for each iteration.
-		task := Task{
-			ID: i, // Assigns a sequential ID to the task.
+// This method is designed to be run in its own goroutine. It stops early if ctx
+// is cancelled; in every case TaskChan is closed, or Input.Done called, before
+// Start returns.
+func (p *TypedProducer[In, Out]) Start(ctx context.Context) {
+	produce(ctx, production[In, Out]{
+		next:         sequence(p.TaskCount, p.FirstID, p.Generate),
+		taskChan:     p.TaskChan,
+		input:        p.Input,
+		blockedNanos: &p.blockedNanos,
+		logger:       p.Logger,
+		limiter:      p.Limiter,
+	})
+}
+
+// BlockedTime returns how long the producer has been blocked on TaskChan so far.
+func (p *TypedProducer[In, Out]) BlockedTime() time.Duration {
+	return time.Duration(p.blockedNanos.Load())
+}
+
+// production holds what the producers hand to produce.
+type production[In, Out any] struct {
+	next         func() (TypedTask[In, Out], bool) // Returns the next task to send, or false once there are none left.
+	taskChan     chan<- TypedTask[In, Out]         // Where the tasks are sent; closed by produce.
+	input        *TypedPoolInput[In, Out]          // If set, where the tasks are submitted instead of taskChan; produce calls Done.
+	blockedNanos *atomic.Int64                     // Accumulates the time spent blocked on taskChan.
+	logger       *slog.Logger                      // Receives the events; nil logs nothing.
+	limiter      *RateLimiter                      // Paces the tasks; nil leaves them unpaced.
+}
+
+// sequence returns a next function for production that builds taskCount tasks
+// with generate, with IDs from firstID on.
+func sequence[In, Out any](taskCount, firstID int, generate func(id int) TypedTask[In, Out]) func() (TypedTask[In, Out], bool) {
+	i := 0
+	return func() (TypedTask[In, Out], bool) {
+		if i >= taskCount {
+			return TypedTask[In, Out]{}, false
+		}
+		i++
+		return generate(firstID + i - 1), true
+	}
+}
+
+// produce is the generation loop shared by the producers.
+// It sends the tasks returned by next to taskChan, or submits them to input,
+// until next reports that there are none left, at the pace allowed by the
+// limiter, adds the time spent blocked on each send to blockedNanos, and closes
+// taskChan (or calls input.Done) before returning.
+func produce[In, Out any](ctx context.Context, p production[In, Out]) {
+	if p.input != nil {
+		produceInput(ctx, p)
+		return
+	}
+	taskChan, blockedNanos := p.taskChan, p.blockedNanos
+	logger, limiter := loggerOrDiscard(p.logger), p.limiter
 
-			// Generates a random integer for the task's data.
-			// Intn(1000000) generates numbers from 0 to 999999. Adding 1 makes it 1 to 1000000.
-			Data: p.RandomNumber.Intn(1000000) + 1,
+	// Closing the channel signals to all listening workers that no more tasks
+	// will be sent, allowing them to gracefully exit their loops. Deferring it
+	// guarantees the close also happens when generation is aborted.
+	defer close(taskChan)
+
+	// Loop until every task has been generated and sent.
+	sent := 0
+	for ; ; sent++ {
+		task, ok := p.next()
+		if !ok {
+			break
+		}
+
+		// Waiting for the rate limiter is not counted as blocked time: the
+		// producer is held back by its own pace, not by the workers.
+		if err := limiter.Wait(ctx); err != nil {
+			logger.InfoContext(ctx, "producer cancelled", "sent", sent, "error", err)
+			return
+		}
+		task.sentAt = time.Now() // Starts the task's enqueue span when tracing is enabled.
 
-			// Generates a random complexity (simulated processing time) for the task.
-			// Intn(195) generates numbers from 0 to 194. Adding 5 makes it 5 to 199.
-			// Multiplied by time.Millisecond to convert to a time.Duration.
-			Complexity: time.Duration(p.RandomNumber.Intn(195)+5) * time.Millisecond,
+		// Try the send without blocking first: if a worker is already waiting,
+		// the producer was not held back and there is nothing to measure.
+		select {
+		case taskChan <- task:
+			logger.LogAttrs(ctx, slog.LevelDebug, "task sent", slog.Int("task", task.ID))
+			continue
+		default:
 		}
 
 		// Send the newly created task to the TaskChan.
-		// Since TaskChan is unbuffered (as defined in Pool), this send operation
+		// Since TaskChan is unbuffered (as defined in the pool), this send operation
 		// will block if no worker is ready to receive the task. This mechanism
 		// provides backpressure, preventing the producer from overwhelming the workers.
-		p.TaskChan <- task
+		// The select also watches ctx so that a cancelled run never leaves the
+		// producer blocked on a send that no worker will ever receive.
+		blockedAt := time.Now()
+		select {
+		case taskChan <- task:
+			blocked := time.Since(blockedAt)
+			blockedNanos.Add(int64(blocked))
+			logger.LogAttrs(ctx, slog.LevelDebug, "task sent", slog.Int("task", task.ID), slog.Duration("blocked", blocked))
+		case <-ctx.Done():
+			logger.InfoContext(ctx, "producer cancelled", "sent", sent, "error", ctx.Err())
+			return
+		}
+	}
+	logger.InfoContext(ctx, "producer finished", "sent", sent, "blocked", time.Duration(blockedNanos.Load()))
+}
+
+// produceInput is produce for a producer that shares the pool's input with
+// others: the tasks go through the pool's Submit, which never sends on a closed
+// TaskChan, and only the last producer to call input.Done closes it.
+func produceInput[In, Out any](ctx context.Context, p production[In, Out]) {
+	pool, blockedNanos := p.input.pool, p.blockedNanos
+	logger, limiter := loggerOrDiscard(p.logger), p.limiter
+	defer p.input.Done()
+
+	sent := 0
+	for ; ; sent++ {
+		task, ok := p.next()
+		if !ok {
+			break
+		}
+		if err := limiter.Wait(ctx); err != nil {
+			logger.InfoContext(ctx, "producer cancelled", "sent", sent, "error", err)
+			return
+		}
+		task.sentAt = time.Now()
+
+		// As in produce, only a submission that has to wait counts as blocked.
+		accepted, err := pool.send(ctx, task, false)
+		if !accepted && err == nil {
+			blockedAt := time.Now()
+			accepted, err = pool.send(ctx, task, true)
+			blockedNanos.Add(int64(time.Since(blockedAt)))
+		}
+		if err != nil {
+			// The run was cancelled, or the input was closed under the producer.
+			logger.InfoContext(ctx, "producer cancelled", "sent", sent, "error", err)
+			return
+		}
+		logger.LogAttrs(ctx, slog.LevelDebug, "task sent", slog.Int("task", task.ID))
+	}
+	logger.InfoContext(ctx, "producer finished", "sent", sent, "blocked", time.Duration(blockedNanos.Load()))
+}
+
+// Producer generates random prime-checking tasks and sends them to the task channel.
+type Producer struct {
+	TaskCount    int          // The total number of tasks this producer will generate.
+	FirstID      int          // The ID of the first task; the others follow sequentially.
+	TaskChan     chan<- Task  // A send-only channel where the producer sends newly created tasks.
+	Input        *PoolInput   // If set, the tasks are submitted through Input instead of TaskChan (see AddProducer).
+	RandomNumber *rand.Rand   // A source of pseudo-random numbers for generating task data and complexity.
+	Logger       *slog.Logger // Receives an event for every task sent. Nil logs nothing.
+	Limiter      *RateLimiter // Paces the tasks sent. Nil sends them as fast as they are accepted.
+
+	// The ranges the random tasks are drawn from, both bounds included.
+	// MaxData must not be below MinData, nor MaxComplexity below MinComplexity,
+	// and MaxData-MinData must be below math.MaxInt.
+	MinData       int           // Smallest number to check for primality.
+	MaxData       int           // Largest number to check for primality.
+	MinComplexity time.Duration // Shortest simulated processing time.
+	MaxComplexity time.Duration // Longest simulated processing time; the complexity is drawn in whole milliseconds above MinComplexity.
+
+	blockedNanos atomic.Int64 // Total time spent waiting for TaskChan to accept a task.
+}
+
+// NewProducer creates and returns a new Producer instance.
+// It initializes the producer with the total number of tasks to create
+// and the channel through which it will send these tasks. The tasks check
+// numbers from 1 to 1000000 and take 5 to 199 milliseconds to process.
+// The random numbers are seeded from the clock, so every run is different;
+// use NewProducerWithSeed to repeat a run.
+func NewProducer(taskCount int, taskChan chan<- Task) *Producer {
+	// Seeding the generator with the current nanosecond timestamp ensures
+	// different sequences of random numbers on each program run.
+	return NewProducerWithSeed(taskCount, taskChan, time.Now().UnixNano())
+}
+
+// NewProducerWithSeed is like NewProducer, but seeds its random numbers with
+// seed. Two producers created with the same seed, and given the same data and
+// complexity ranges, generate the identical sequence of Task.Data and
+// Task.Complexity values, on every run and every platform.
+func NewProducerWithSeed(taskCount int, taskChan chan<- Task, seed int64) *Producer {
+	return NewProducerWithSource(taskCount, taskChan, rand.NewSource(seed))
+}
+
+// NewProducerWithSource is like NewProducer, but draws its random numbers from
+// src, which must not be used by anything else while the producer runs. A
+// deterministic src yields a deterministic sequence of tasks, as with
+// NewProducerWithSeed.
+func NewProducerWithSource(taskCount int, taskChan chan<- Task, src rand.Source) *Producer {
+	return &Producer{
+		TaskCount: taskCount, // Sets the total number of tasks to be generated.
+		TaskChan:  taskChan,  // Assigns the channel to send tasks.
+		// Initializes a new pseudo-random number generator from the given source.
+		RandomNumber: rand.New(src),
+		// The default ranges of the generated tasks.
+		MinData:       1,
+		MaxData:       1000000,
+		MinComplexity: 5 * time.Millisecond,
+		MaxComplexity: 199 * time.Millisecond,
 	}
+}
 
-	// After all tasks have been sent, close the TaskChan.
-	// Closing the channel signals to all listening workers (via 'for range' loops)
-	// that no more tasks will be sent, allowing them to gracefully exit their loops.
-	close(p.TaskChan)
+// Start begins the task generation process.
+// This method is designed to be run in its own goroutine. It stops early if ctx
+// is cancelled; in every case TaskChan is closed, or Input.Done called, before
+// Start returns.
+func (p *Producer) Start(ctx context.Context) {
+	produce(ctx, production[int, any]{
+		next:         sequence(p.TaskCount, p.FirstID, p.newTask),
+		taskChan:     p.TaskChan,
+		input:        p.Input,
+		blockedNanos: &p.blockedNanos,
+		logger:       p.Logger,
+		limiter:      p.Limiter,
+	})
+}
+
+// BlockedTime returns how long the producer has been blocked on TaskChan so far.
+func (p *Producer) BlockedTime() time.Duration {
+	return time.Duration(p.blockedNanos.Load())
+}
+
+// newTask builds the task with the given ID from the producer's random source.
+// Every task draws exactly two numbers, Data first and then Complexity, so the
+// sequence of tasks only depends on the source and the ranges; changing either
+// the order or the number of draws would break NewProducerWithSeed's guarantee.
+func (p *Producer) newTask(id int) Task {
+	return Task{
+		ID: id, // Assigns a sequential ID to the task.
+
+		// Generates a random integer for the task's data.
+		// Intn(n) generates numbers from 0 to n-1, so adding MinData gives MinData to MaxData.
+		Data: p.MinData + p.RandomNumber.Intn(p.MaxData-p.MinData+1),
+
+		// Generates a random complexity (simulated processing time) for the task,
+		// from MinComplexity to MaxComplexity in the same way, in whole
+		// milliseconds: with the default range this is the original 5 to 199ms.
+		Complexity: p.MinComplexity + time.Duration(p.RandomNumber.Intn(int((p.MaxComplexity-p.MinComplexity)/time.Millisecond)+1))*time.Millisecond,
+	}
 }
@@ -0,0 +1,81 @@
+package exercise02workerpool_test
+
+import (
+	"context"   // Used to run the producer.
+	"math/rand" // Used to give a producer its own source.
+	"testing"   // The testing package is required for tests.
+	"time"      // Used for the complexity range.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// TestProducerRanges checks that the generated tasks stay within the data and
+// complexity ranges set on the producer, bounds included.
+func TestProducerRanges(t *testing.T) {
+	taskChan := make(chan exercise02workerpool.Task, 200)
+	producer := exercise02workerpool.NewProducer(200, taskChan)
+	producer.MinData, producer.MaxData = 10, 12
+	producer.MinComplexity, producer.MaxComplexity = time.Millisecond, 2*time.Millisecond
+	producer.Start(context.Background())
+
+	seen := make(map[int]bool)
+	for task := range taskChan {
+		if task.Data < 10 || task.Data > 12 {
+			t.Errorf("task %d: Data %d is outside 10..12", task.ID, task.Data)
+		}
+		if task.Complexity < time.Millisecond || task.Complexity > 2*time.Millisecond {
+			t.Errorf("task %d: Complexity %v is outside 1ms..2ms", task.ID, task.Complexity)
+		}
+		seen[task.Data] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("got data values %v, want all of 10, 11 and 12", seen)
+	}
+}
+
+// generate runs producer to completion and returns the tasks it sent.
+func generate(producer *exercise02workerpool.Producer, taskChan chan exercise02workerpool.Task) []exercise02workerpool.Task {
+	producer.Start(context.Background())
+	var tasks []exercise02workerpool.Task
+	for task := range taskChan {
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// TestProducerWithSeed checks that a seed always yields the same tasks: the same
+// as another producer with that seed, and the same as in any earlier release.
+func TestProducerWithSeed(t *testing.T) {
+	first := make(chan exercise02workerpool.Task, 100)
+	second := make(chan exercise02workerpool.Task, 100)
+	a := generate(exercise02workerpool.NewProducerWithSeed(100, first, 42), first)
+	b := generate(exercise02workerpool.NewProducerWithSource(100, second, rand.NewSource(42)), second)
+	for i := range a {
+		if a[i].ID != b[i].ID || a[i].Data != b[i].Data || a[i].Complexity != b[i].Complexity {
+			t.Fatalf("task %d differs between two producers seeded with 42: %+v and %+v", i, a[i], b[i])
+		}
+	}
+
+	// Pinning the first tasks guards against a change in how the numbers are
+	// drawn, which would silently change every seeded run. They are the values
+	// the original Intn(1000000)+1 and (Intn(195)+5)ms draws give.
+	want := []struct {
+		data       int
+		complexity time.Duration
+	}{
+		{72306, 22 * time.Millisecond},
+		{281669, 80 * time.Millisecond},
+		{99424, 180 * time.Millisecond},
+	}
+	for i, w := range want {
+		if a[i].Data != w.data || a[i].Complexity != w.complexity {
+			t.Errorf("task %d is (%d, %v), want (%d, %v)", i, a[i].Data, a[i].Complexity, w.data, w.complexity)
+		}
+	}
+
+	other := make(chan exercise02workerpool.Task, 100)
+	c := generate(exercise02workerpool.NewProducerWithSeed(100, other, 43), other)
+	if c[0].Data == a[0].Data && c[1].Data == a[1].Data && c[2].Data == a[2].Data {
+		t.Error("seeds 42 and 43 generated the same tasks")
+	}
+}
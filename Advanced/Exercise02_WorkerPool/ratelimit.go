@@ -0,0 +1,84 @@
+package exercise02workerpool
+
+import (
+	"context" // Package for cancelling a wait for a token.
+	"sync"    // Package for the mutex guarding the bucket.
+	"time"    // Package for refilling the bucket over time.
+)
+
+// RateLimiter is a token bucket: it allows Rate events per second on average,
+// with bursts of up to Burst events. It can be shared by several producers and
+// pools, and is safe for concurrent use. A nil *RateLimiter allows everything.
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64   // Tokens added per second.
+	burst  float64   // Capacity of the bucket.
+	tokens float64   // Tokens available; negative when waiters have reserved future tokens.
+	last   time.Time // When tokens was last brought up to date.
+}
+
+// NewRateLimiter returns a limiter allowing rate events per second with bursts
+// of up to burst events (at least 1). The bucket starts full. A rate of zero or
+// less disables limiting.
+func NewRateLimiter(rate float64, burst int) *RateLimiter {
+	burst = max(burst, 1)
+	return &RateLimiter{rate: rate, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// Allow takes a token if one is available right now and reports whether it did.
+func (l *RateLimiter) Allow() bool {
+	if l == nil || l.rate <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill(time.Now())
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Wait blocks until a token is available and takes it. It returns ctx.Err()
+// without taking a token if ctx is done first.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+
+	// Reserve the token straight away, so that concurrent waiters queue up
+	// behind each other instead of all waking up for the same token.
+	l.mu.Lock()
+	now := time.Now()
+	l.refill(now)
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// Give the reserved token back to whoever waits next.
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// refill adds the tokens earned since the last update. The caller must hold l.mu.
+func (l *RateLimiter) refill(now time.Time) {
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = min(l.tokens+elapsed.Seconds()*l.rate, l.burst)
+		l.last = now
+	}
+}
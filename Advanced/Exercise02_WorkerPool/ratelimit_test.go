@@ -0,0 +1,88 @@
+package exercise02workerpool_test
+
+import (
+	"context" // Used to cancel waits and runs.
+	"errors"  // Used to compare errors against context errors.
+	"testing" // The testing package is required for tests.
+	"time"    // Used to measure the pace.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// TestRateLimiter checks bursts, pacing and cancellation of a single limiter.
+func TestRateLimiter(t *testing.T) {
+	limiter := exercise02workerpool.NewRateLimiter(50, 3)
+	for i := 0; i < 3; i++ {
+		if !limiter.Allow() {
+			t.Fatalf("token %d of the burst was refused", i)
+		}
+	}
+	if limiter.Allow() {
+		t.Fatal("a token was allowed beyond the burst")
+	}
+
+	// The bucket is empty: five more tokens take about 100ms at 50 per second.
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("five tokens took %v, want about 100ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	slow := exercise02workerpool.NewRateLimiter(0.1, 1)
+	slow.Allow()
+	if err := slow.Wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait returned %v, want context.DeadlineExceeded", err)
+	}
+
+	var unlimited *exercise02workerpool.RateLimiter
+	if !unlimited.Allow() || unlimited.Wait(context.Background()) != nil {
+		t.Error("a nil limiter must allow everything")
+	}
+}
+
+// TestRateLimitedPoolAndProducer checks that a limited producer and a limited
+// pool both pace the run, and that a cancelled producer stops waiting.
+func TestRateLimitedPoolAndProducer(t *testing.T) {
+	// Ten tasks, bursts of one: nine waits of 10ms on the pool side.
+	pool := exercise02workerpool.NewPool(4, exercise02workerpool.ProcessorFunc(
+		func(ctx context.Context, task exercise02workerpool.Task) (any, error) { return nil, nil },
+	), exercise02workerpool.WithRateLimit(exercise02workerpool.NewRateLimiter(100, 1)))
+	pool.Start(context.Background())
+
+	start := time.Now()
+	go func() {
+		for i := 0; i < 10; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i}
+		}
+		close(pool.TaskChan)
+	}()
+	if results := collect(t, pool.ResultChan); len(results) != 10 {
+		t.Fatalf("got %d results, want 10", len(results))
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("the limited pool finished in %v, want about 90ms", elapsed)
+	}
+
+	// A producer limited to one task per second only sends its burst before
+	// the context is cancelled, and still closes its channel.
+	taskChan := make(chan exercise02workerpool.Task, 10)
+	producer := exercise02workerpool.NewProducer(10, taskChan)
+	producer.Limiter = exercise02workerpool.NewRateLimiter(1, 2)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	producer.Start(ctx)
+
+	sent := 0
+	for range taskChan {
+		sent++
+	}
+	if sent != 2 {
+		t.Errorf("the limited producer sent %d tasks, want 2", sent)
+	}
+}
@@ -0,0 +1,70 @@
+package exercise02workerpool
+
+import (
+	"context"   // Package for recognising cancellation, which is never retried.
+	"errors"    // Package for inspecting wrapped errors.
+	"math/rand" // Package for the random jitter added to backoff delays.
+	"time"      // Package for backoff durations.
+)
+
+// RetryPolicy controls how a pool retries tasks whose processing failed.
+// The zero value disables retries. Zero fields select the defaults documented
+// on each of them.
+type RetryPolicy struct {
+	MaxAttempts    int              // Total attempts per task, including the first. Below 2 disables retries.
+	InitialBackoff time.Duration    // Delay before the second attempt. Defaults to 100ms.
+	MaxBackoff     time.Duration    // Upper bound for any delay. Defaults to 30s.
+	Multiplier     float64          // Growth factor between consecutive delays. Defaults to 2.
+	Jitter         float64          // Fraction (0..1) of each delay that is randomised, to spread retries out.
+	Retryable      func(error) bool // Decides whether an error is worth retrying. Defaults to DefaultRetryable.
+}
+
+// DefaultRetryable is the retry predicate used when RetryPolicy.Retryable is nil.
+// Every error is retried except context cancellation, since a cancelled run is
+// over, and recovered panics, which point at a bug rather than a passing failure.
+func DefaultRetryable(err error) bool {
+	var panicErr *PanicError
+	return !errors.Is(err, context.Canceled) && !errors.As(err, &panicErr)
+}
+
+// ShouldRetry reports whether a task that has been attempted attempts times and
+// failed with err should be attempted again.
+func (r RetryPolicy) ShouldRetry(err error, attempts int) bool {
+	if err == nil || attempts >= r.MaxAttempts {
+		return false
+	}
+	retryable := r.Retryable
+	if retryable == nil {
+		retryable = DefaultRetryable
+	}
+	return retryable(err)
+}
+
+// Backoff returns the delay to wait before the next attempt of a task that has
+// been attempted attempts times: InitialBackoff after the first attempt, then
+// growing by Multiplier each time, capped at MaxBackoff, with Jitter applied.
+func (r RetryPolicy) Backoff(attempts int) time.Duration {
+	initial, maxBackoff, multiplier := r.InitialBackoff, r.MaxBackoff, r.Multiplier
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = 30 * time.Second
+	}
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	delay := float64(initial)
+	for i := 1; i < attempts && delay < float64(maxBackoff); i++ {
+		delay *= multiplier
+	}
+	delay = min(delay, float64(maxBackoff))
+
+	// With Jitter j, the delay is drawn uniformly from [delay*(1-j), delay].
+	// The top-level math/rand functions are safe for concurrent use.
+	if jitter := min(max(r.Jitter, 0), 1); jitter > 0 {
+		delay -= delay * jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
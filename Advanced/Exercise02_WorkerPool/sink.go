@@ -0,0 +1,341 @@
+package exercise02workerpool
+
+import (
+	"bufio"          // Package for buffering the output of the sinks.
+	"encoding/csv"   // Package for writing CSV records.
+	"encoding/json"  // Package for writing JSON Lines records.
+	"fmt"            // Package for formatting the data and results of the tasks.
+	"io"             // Package for the writers the sinks write to.
+	"os"             // Package for creating result files and writing to stdout.
+	"strconv"        // Package for formatting IDs and attempt counts.
+	"text/tabwriter" // Package for aligning the columns of a table.
+	"time"           // Package for formatting the complexity of the tasks.
+)
+
+// ResultSink receives the finished tasks of a run, e.g. to write them to a file.
+// A TypedConsumer writes every result it receives to its Sink.
+type ResultSink[In, Out any] interface {
+	// Write records one finished task. An error means the result was not
+	// recorded; once writing to its destination failed, a sink keeps failing.
+	Write(TypedTask[In, Out]) error
+	// Close writes out anything still buffered and releases the sink.
+	Close() error
+}
+
+// sinkOutput is the buffered destination shared by the built-in sinks. Errors
+// are sticky: once writing failed, every later call reports the same error.
+type sinkOutput struct {
+	buf    *bufio.Writer
+	closer io.Closer // The file created by one of the Create functions, if any.
+	err    error
+}
+
+// newSinkOutput buffers w. closer, if not nil, is closed by close.
+func newSinkOutput(w io.Writer, closer io.Closer) sinkOutput {
+	return sinkOutput{buf: bufio.NewWriter(w), closer: closer}
+}
+
+// fail records err, unless an earlier error was recorded, and returns the first error.
+func (o *sinkOutput) fail(err error) error {
+	if o.err == nil {
+		o.err = err
+	}
+	return o.err
+}
+
+// flush writes out the buffered output.
+func (o *sinkOutput) flush() error {
+	if o.err != nil {
+		return o.err
+	}
+	if err := o.buf.Flush(); err != nil {
+		return o.fail(err)
+	}
+	return nil
+}
+
+// close flushes the output and closes the file it was created with, if any.
+func (o *sinkOutput) close() error {
+	err := o.flush()
+	if o.closer != nil {
+		if closeErr := o.closer.Close(); err == nil {
+			err = closeErr
+		}
+		o.closer = nil
+	}
+	return err
+}
+
+// formatComplexity formats a task's Complexity for the sinks, leaving out a zero one.
+func formatComplexity(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
+
+// TypedJSONLinesSink writes every result as a JSON object on a line of its own,
+// with the fields "id", "data", "complexity" (e.g. "150ms"), "result" (left out
+// when the task failed), "error" (left out when it succeeded) and "attempts".
+// The output is a valid input for TypedJSONLinesSource, which ignores the fields
+// it does not know. Output is buffered until Flush or Close.
+type TypedJSONLinesSink[In, Out any] struct {
+	out sinkOutput
+	enc *json.Encoder
+}
+
+// resultRecord is one line written by a TypedJSONLinesSink.
+type resultRecord[In, Out any] struct {
+	ID         int    `json:"id"`
+	Data       In     `json:"data"`
+	Complexity string `json:"complexity,omitempty"`
+	Result     *Out   `json:"result,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Attempts   int    `json:"attempts"`
+}
+
+// NewTypedJSONLinesSink creates a TypedJSONLinesSink writing to w. Close does
+// not close w.
+func NewTypedJSONLinesSink[In, Out any](w io.Writer) *TypedJSONLinesSink[In, Out] {
+	return newJSONLinesSink[In, Out](w, nil)
+}
+
+// NewJSONLinesSink creates a TypedJSONLinesSink for prime-checking results.
+func NewJSONLinesSink(w io.Writer) *TypedJSONLinesSink[int, any] {
+	return NewTypedJSONLinesSink[int, any](w)
+}
+
+// CreateJSONLinesSink creates the file at path, truncating it if it exists, and
+// writes prime-checking results to it like NewJSONLinesSink. Close closes the file.
+func CreateJSONLinesSink(path string) (*TypedJSONLinesSink[int, any], error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return newJSONLinesSink[int, any](file, file), nil
+}
+
+func newJSONLinesSink[In, Out any](w io.Writer, closer io.Closer) *TypedJSONLinesSink[In, Out] {
+	s := &TypedJSONLinesSink[In, Out]{out: newSinkOutput(w, closer)}
+	s.enc = json.NewEncoder(s.out.buf)
+	return s
+}
+
+// Write implements ResultSink.
+func (s *TypedJSONLinesSink[In, Out]) Write(task TypedTask[In, Out]) error {
+	if s.out.err != nil {
+		return s.out.err
+	}
+	record := resultRecord[In, Out]{
+		ID:         task.ID,
+		Data:       task.Data,
+		Complexity: formatComplexity(task.Complexity),
+		Attempts:   task.Attempts,
+	}
+	if task.Err != nil {
+		record.Error = task.Err.Error()
+	} else {
+		record.Result = &task.Result
+	}
+	// A value that cannot be encoded only fails its own task: nothing of it is
+	// written. Errors of the writer stick in the bufio.Writer.
+	if err := s.enc.Encode(record); err != nil {
+		return fmt.Errorf("workerpool: writing the result of task %d: %w", task.ID, err)
+	}
+	return nil
+}
+
+// Flush writes out the buffered results.
+func (s *TypedJSONLinesSink[In, Out]) Flush() error {
+	return s.out.flush()
+}
+
+// Close implements ResultSink.
+func (s *TypedJSONLinesSink[In, Out]) Close() error {
+	return s.out.close()
+}
+
+// csvHeader is the header written by a TypedCSVSink.
+var csvHeader = []string{"id", "data", "complexity", "result", "error", "attempts"}
+
+// TypedCSVSink writes every result as a CSV record under the header
+// id,data,complexity,result,error,attempts. Data and Result are formatted with
+// fmt.Sprint; the result is empty when the task failed and the error when it
+// succeeded. The output is a valid input for TypedCSVSource. Output is buffered
+// until Flush or Close.
+type TypedCSVSink[In, Out any] struct {
+	out    sinkOutput
+	writer *csv.Writer
+	header bool // Whether the header was written.
+}
+
+// NewTypedCSVSink creates a TypedCSVSink writing to w. Close does not close w.
+func NewTypedCSVSink[In, Out any](w io.Writer) *TypedCSVSink[In, Out] {
+	return newCSVSink[In, Out](w, nil)
+}
+
+// NewCSVSink creates a TypedCSVSink for prime-checking results.
+func NewCSVSink(w io.Writer) *TypedCSVSink[int, any] {
+	return NewTypedCSVSink[int, any](w)
+}
+
+// CreateCSVSink creates the file at path, truncating it if it exists, and
+// writes prime-checking results to it like NewCSVSink. Close closes the file.
+func CreateCSVSink(path string) (*TypedCSVSink[int, any], error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return newCSVSink[int, any](file, file), nil
+}
+
+func newCSVSink[In, Out any](w io.Writer, closer io.Closer) *TypedCSVSink[In, Out] {
+	s := &TypedCSVSink[In, Out]{out: newSinkOutput(w, closer)}
+	s.writer = csv.NewWriter(s.out.buf)
+	return s
+}
+
+// Write implements ResultSink.
+func (s *TypedCSVSink[In, Out]) Write(task TypedTask[In, Out]) error {
+	if s.out.err != nil {
+		return s.out.err
+	}
+	if !s.header {
+		s.header = true
+		s.writer.Write(csvHeader) // Errors resurface through the writer below.
+	}
+	record := []string{strconv.Itoa(task.ID), fmt.Sprint(task.Data), formatComplexity(task.Complexity), "", "", strconv.Itoa(task.Attempts)}
+	if task.Err != nil {
+		record[4] = task.Err.Error()
+	} else {
+		record[3] = fmt.Sprint(task.Result)
+	}
+	if err := s.writer.Write(record); err != nil {
+		return s.out.fail(fmt.Errorf("workerpool: writing the result of task %d: %w", task.ID, err))
+	}
+	return nil
+}
+
+// Flush writes out the buffered results.
+func (s *TypedCSVSink[In, Out]) Flush() error {
+	if s.out.err != nil {
+		return s.out.err
+	}
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		return s.out.fail(err)
+	}
+	return s.out.flush()
+}
+
+// Close implements ResultSink.
+func (s *TypedCSVSink[In, Out]) Close() error {
+	err := s.Flush()
+	if closeErr := s.out.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// TypedTableSink writes the results as a table for people to read, with the
+// columns ID, DATA, COMPLEXITY, RESULT, ATTEMPTS and ERROR aligned. Aligning a
+// column takes its widest cell, so the rows are held in memory and the whole
+// table is written by Flush or Close; each Flush starts a new table.
+type TypedTableSink[In, Out any] struct {
+	out    sinkOutput
+	table  *tabwriter.Writer
+	header bool // Whether the current table has its header.
+}
+
+// NewTypedTableSink creates a TypedTableSink writing to w. Close does not close w.
+func NewTypedTableSink[In, Out any](w io.Writer) *TypedTableSink[In, Out] {
+	return newTableSink[In, Out](w, nil)
+}
+
+// NewTableSink creates a TypedTableSink for prime-checking results.
+func NewTableSink(w io.Writer) *TypedTableSink[int, any] {
+	return NewTypedTableSink[int, any](w)
+}
+
+// CreateTableSink creates the file at path, truncating it if it exists, and
+// writes prime-checking results to it like NewTableSink. Close closes the file.
+func CreateTableSink(path string) (*TypedTableSink[int, any], error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return newTableSink[int, any](file, file), nil
+}
+
+func newTableSink[In, Out any](w io.Writer, closer io.Closer) *TypedTableSink[In, Out] {
+	s := &TypedTableSink[In, Out]{out: newSinkOutput(w, closer)}
+	s.table = tabwriter.NewWriter(s.out.buf, 0, 8, 2, ' ', 0)
+	return s
+}
+
+// Write implements ResultSink.
+func (s *TypedTableSink[In, Out]) Write(task TypedTask[In, Out]) error {
+	if s.out.err != nil {
+		return s.out.err
+	}
+	if !s.header {
+		s.header = true
+		fmt.Fprintln(s.table, "ID\tDATA\tCOMPLEXITY\tRESULT\tATTEMPTS\tERROR\t")
+	}
+	result, errText := fmt.Sprint(task.Result), ""
+	if task.Err != nil {
+		result, errText = "-", task.Err.Error()
+	}
+	complexity := formatComplexity(task.Complexity)
+	if complexity == "" {
+		complexity = "-"
+	}
+	// A tabwriter only fails when it writes, i.e. on Flush.
+	fmt.Fprintf(s.table, "%d\t%v\t%s\t%s\t%d\t%s\t\n", task.ID, task.Data, complexity, result, task.Attempts, errText)
+	return nil
+}
+
+// Flush writes the table of the results received since the last Flush.
+func (s *TypedTableSink[In, Out]) Flush() error {
+	if s.out.err != nil {
+		return s.out.err
+	}
+	s.header = false
+	if err := s.table.Flush(); err != nil {
+		return s.out.fail(err)
+	}
+	return s.out.flush()
+}
+
+// Close implements ResultSink.
+func (s *TypedTableSink[In, Out]) Close() error {
+	err := s.Flush()
+	if closeErr := s.out.close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// primeTextSink writes prime-checking results in the format Consumer has always
+// printed, one line per task.
+type primeTextSink struct {
+	w io.Writer
+}
+
+// Write implements ResultSink.
+func (s primeTextSink) Write(task Task) error {
+	if task.Err != nil {
+		_, err := fmt.Fprintf(s.w, "Task   %d\t Data = %d\t error = %v\n", task.ID, task.Data, task.Err)
+		return err
+	}
+
+	// Print the details of the processed task to the console.
+	// This includes the task ID, its original data, and the calculated result (e.g., isPrime).
+	_, err := fmt.Fprintf(s.w, "Task   %d\t Data = %d\t isPrime = %v\n", task.ID, task.Data, task.Result)
+	return err
+}
+
+// Close implements ResultSink. The text is written unbuffered, so there is nothing to do.
+func (primeTextSink) Close() error {
+	return nil
+}
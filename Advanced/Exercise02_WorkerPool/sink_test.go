@@ -0,0 +1,116 @@
+package exercise02workerpool_test
+
+import (
+	"bytes"         // Used to capture the output of the sinks.
+	"context"       // Used to run the consumers.
+	"errors"        // Used to build failed tasks and failing writers.
+	"path/filepath" // Used to build the path of a result file.
+	"strings"       // Used to inspect the output.
+	"testing"       // The testing package is required for tests.
+	"time"          // Used for task complexities.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// sinkResults returns a closed channel holding a successful and a failed task.
+func sinkResults() chan exercise02workerpool.Task {
+	results := make(chan exercise02workerpool.Task, 2)
+	results <- exercise02workerpool.Task{ID: 1, Data: 7, Complexity: 5 * time.Millisecond, Result: true, Attempts: 1}
+	results <- exercise02workerpool.Task{ID: 2, Data: 8, Err: errors.New("boom"), Attempts: 3}
+	close(results)
+	return results
+}
+
+// TestResultSinks checks the output of every built-in sink, written through a
+// Consumer, and that the files they write can be read back as task sources.
+func TestResultSinks(t *testing.T) {
+	tests := []struct {
+		name string
+		sink func(*bytes.Buffer) exercise02workerpool.ResultSink[int, any]
+		want string
+	}{
+		{
+			name: "jsonl",
+			sink: func(b *bytes.Buffer) exercise02workerpool.ResultSink[int, any] {
+				return exercise02workerpool.NewJSONLinesSink(b)
+			},
+			want: `{"id":1,"data":7,"complexity":"5ms","result":true,"attempts":1}` + "\n" +
+				`{"id":2,"data":8,"error":"boom","attempts":3}` + "\n",
+		},
+		{
+			name: "csv",
+			sink: func(b *bytes.Buffer) exercise02workerpool.ResultSink[int, any] {
+				return exercise02workerpool.NewCSVSink(b)
+			},
+			want: "id,data,complexity,result,error,attempts\n1,7,5ms,true,,1\n2,8,,,boom,3\n",
+		},
+		{
+			name: "table",
+			sink: func(b *bytes.Buffer) exercise02workerpool.ResultSink[int, any] {
+				return exercise02workerpool.NewTableSink(b)
+			},
+			want: "ID  DATA  COMPLEXITY  RESULT  ATTEMPTS  ERROR  \n" +
+				"1   7     5ms         true    1                \n" +
+				"2   8     -           -       3         boom   \n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			consumer := exercise02workerpool.NewConsumer(sinkResults())
+			consumer.Sink = tt.sink(&out)
+			if err := consumer.Start(context.Background()); err != nil {
+				t.Fatalf("Start returned %v", err)
+			}
+			if out.String() != tt.want {
+				t.Errorf("got output\n%s\nwant\n%s", out.String(), tt.want)
+			}
+		})
+	}
+
+	// The results written to a file read back as tasks.
+	path := filepath.Join(t.TempDir(), "results.csv")
+	sink, err := exercise02workerpool.CreateCSVSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumer := exercise02workerpool.NewConsumer(sinkResults())
+	consumer.Sink = sink
+	if err := consumer.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned %v", err)
+	}
+	source, err := exercise02workerpool.OpenCSVSource(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, lines := readSource(t, source)
+	if len(tasks) != 2 || len(lines) != 0 || tasks[0].Data != 7 || tasks[0].Complexity != 5*time.Millisecond || tasks[1].ID != 2 {
+		t.Errorf("read back %+v with malformed lines %v", tasks, lines)
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+// TestConsumerSinkError checks that a failing sink is reported by Start, and
+// that the consumer still receives every result.
+func TestConsumerSinkError(t *testing.T) {
+	results := make(chan exercise02workerpool.Task, 100)
+	for i := 0; i < 100; i++ {
+		results <- exercise02workerpool.Task{ID: i, Data: i, Result: strings.Repeat("x", 100)}
+	}
+	close(results)
+
+	received := 0
+	consumer := exercise02workerpool.NewTypedConsumer(results, func(exercise02workerpool.Task) { received++ })
+	consumer.Sink = exercise02workerpool.NewJSONLinesSink(failingWriter{})
+	n, err := consumer.Start(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf("Start returned %v, want the writer's error", err)
+	}
+	if n != 100 || received != 100 {
+		t.Errorf("received %d results (Handle saw %d), want 100", n, received)
+	}
+}
@@ -0,0 +1,408 @@
+package exercise02workerpool
+
+import (
+	"bufio"         // Package for reading JSON Lines line by line.
+	"bytes"         // Package for skipping blank lines.
+	"context"       // Package for cancellation signals that stop reading early.
+	"encoding/csv"  // Package for parsing CSV records.
+	"encoding/json" // Package for parsing JSON Lines records.
+	"errors"        // Package for recognising CSV parse errors.
+	"fmt"           // Package for describing malformed records.
+	"io"            // Package for the readers the sources consume and io.EOF.
+	"log/slog"      // Package for the structured events logged for malformed records.
+	"os"            // Package for opening task files and reading stdin.
+	"strconv"       // Package for parsing IDs and the data of prime-checking tasks.
+	"strings"       // Package for normalising CSV cells and header names.
+	"sync/atomic"   // Package for the counters read while the producer runs.
+	"time"          // Package for the duration fields of a task.
+)
+
+// TaskSource yields the tasks of a run one at a time, e.g. read from a file.
+type TaskSource[In, Out any] interface {
+	// Next returns the next task. It returns io.EOF once the source is exhausted
+	// and a *LineError for a malformed record, after which the source goes on with
+	// the next record. Any other error means the source cannot continue.
+	Next() (TypedTask[In, Out], error)
+}
+
+// LineError reports a malformed record of a TaskSource and where it is.
+type LineError struct {
+	Line int   // Line number of the record, starting at 1.
+	Err  error // What is wrong with it.
+}
+
+// Error implements the error interface.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// TypedSourceProducer sends the tasks of a TaskSource to the task channel, the
+// way TypedProducer sends generated ones: TaskChan is closed once the source is
+// exhausted, fails, or the run is cancelled. Malformed records are skipped and
+// reported instead of aborting the run. A source that is also an io.Closer is
+// closed when the producer is done with it.
+type TypedSourceProducer[In, Out any] struct {
+	Source      TaskSource[In, Out]       // Where the tasks are read from.
+	TaskChan    chan<- TypedTask[In, Out] // A send-only channel where the producer sends the tasks read.
+	Input       *TypedPoolInput[In, Out]  // If set, the tasks are submitted through Input instead of TaskChan (see AddProducer).
+	OnMalformed func(*LineError)          // Called for every malformed record skipped, in the producer's goroutine. Nil only logs them.
+	Logger      *slog.Logger              // Receives an event for every task sent and malformed record. Nil logs nothing.
+	Limiter     *RateLimiter              // Paces the tasks sent. Nil sends them as fast as they are accepted.
+
+	blockedNanos atomic.Int64 // Total time spent waiting for TaskChan to accept a task.
+	malformed    atomic.Int64 // Number of malformed records skipped.
+}
+
+// SourceProducer is a TypedSourceProducer for the prime-checking Task.
+type SourceProducer = TypedSourceProducer[int, any]
+
+// NewTypedSourceProducer creates a TypedSourceProducer that sends the tasks of
+// source to taskChan.
+func NewTypedSourceProducer[In, Out any](source TaskSource[In, Out], taskChan chan<- TypedTask[In, Out]) *TypedSourceProducer[In, Out] {
+	return &TypedSourceProducer[In, Out]{
+		Source:   source,   // Assigns the source of the tasks.
+		TaskChan: taskChan, // Assigns the channel to send tasks.
+	}
+}
+
+// NewSourceProducer creates a SourceProducer that sends the tasks of source to taskChan.
+func NewSourceProducer(source TaskSource[int, any], taskChan chan<- Task) *SourceProducer {
+	return NewTypedSourceProducer(source, taskChan)
+}
+
+// Start reads the source and sends its tasks until the source is exhausted.
+// This method is designed to be run in its own goroutine. It stops early if ctx
+// is cancelled, although a read already waiting for input (e.g. on stdin) is not
+// interrupted; in every case TaskChan is closed, or Input.Done called, before
+// Start returns. Start returns the error that stopped the source before it was
+// exhausted, if any.
+func (p *TypedSourceProducer[In, Out]) Start(ctx context.Context) error {
+	logger := loggerOrDiscard(p.Logger)
+	if closer, ok := p.Source.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	var err error
+	next := func() (TypedTask[In, Out], bool) {
+		for {
+			task, readErr := p.Source.Next()
+			var lineErr *LineError
+			switch {
+			case readErr == nil:
+				return task, true
+			case errors.As(readErr, &lineErr):
+				// A malformed record costs only itself: report it and read on.
+				p.malformed.Add(1)
+				logger.WarnContext(ctx, "malformed task skipped", "line", lineErr.Line, "error", lineErr.Err)
+				if p.OnMalformed != nil {
+					p.OnMalformed(lineErr)
+				}
+			case readErr != io.EOF:
+				err = readErr
+				logger.ErrorContext(ctx, "task source failed", "error", readErr)
+				return task, false
+			default:
+				return task, false
+			}
+		}
+	}
+
+	produce(ctx, production[In, Out]{
+		next:         next,
+		taskChan:     p.TaskChan,
+		input:        p.Input,
+		blockedNanos: &p.blockedNanos,
+		logger:       p.Logger,
+		limiter:      p.Limiter,
+	})
+	return err
+}
+
+// BlockedTime returns how long the producer has been blocked on TaskChan so far.
+func (p *TypedSourceProducer[In, Out]) BlockedTime() time.Duration {
+	return time.Duration(p.blockedNanos.Load())
+}
+
+// Malformed returns the number of malformed records skipped so far.
+func (p *TypedSourceProducer[In, Out]) Malformed() int {
+	return int(p.malformed.Load())
+}
+
+// TypedCSVSource reads tasks from CSV records. The first record is a header
+// naming the columns: "data" is required, while "id", "complexity", "timeout"
+// and "trace_id" are optional, in any order; other columns are ignored.
+// Durations are written the way time.ParseDuration accepts them (e.g. "150ms")
+// and an empty cell leaves its field at zero. Without an "id" column, or with an
+// empty id, a task's ID is the index of its record, starting at 0 after the header.
+type TypedCSVSource[In, Out any] struct {
+	ParseData func(string) (In, error) // Converts a "data" cell to the task's input.
+
+	reader  *csv.Reader
+	closer  io.Closer      // The file opened by OpenCSVSource, if any.
+	columns map[string]int // Position of every known column, once the header is read.
+	records int            // Number of records read after the header.
+}
+
+// csvColumns are the columns a TypedCSVSource understands.
+var csvColumns = []string{"id", "data", "complexity", "timeout", "trace_id"}
+
+// NewTypedCSVSource creates a TypedCSVSource reading r, which converts the
+// "data" cells with parseData.
+func NewTypedCSVSource[In, Out any](r io.Reader, parseData func(string) (In, error)) *TypedCSVSource[In, Out] {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1 // Short records are reported by Next, with their line.
+	reader.TrimLeadingSpace = true
+	return &TypedCSVSource[In, Out]{ParseData: parseData, reader: reader}
+}
+
+// NewCSVSource creates a TypedCSVSource reading prime-checking tasks from r,
+// whose "data" cells are integers.
+func NewCSVSource(r io.Reader) *TypedCSVSource[int, any] {
+	return NewTypedCSVSource[int, any](r, strconv.Atoi)
+}
+
+// OpenCSVSource opens the file at path and reads prime-checking tasks from it
+// like NewCSVSource. Close closes the file.
+func OpenCSVSource(path string) (*TypedCSVSource[int, any], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	source := NewCSVSource(file) // csv.Reader buffers its input.
+	source.closer = file
+	return source, nil
+}
+
+// Next implements TaskSource.
+func (s *TypedCSVSource[In, Out]) Next() (TypedTask[In, Out], error) {
+	if s.columns == nil {
+		if err := s.readHeader(); err != nil {
+			return TypedTask[In, Out]{}, err
+		}
+	}
+
+	record, err := s.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		s.records++
+		return TypedTask[In, Out]{}, &LineError{Line: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return TypedTask[In, Out]{}, err // io.EOF, or a read error.
+	}
+	index := s.records
+	s.records++
+
+	task, err := s.parse(record, index)
+	if err != nil {
+		line, _ := s.reader.FieldPos(0)
+		return TypedTask[In, Out]{}, &LineError{Line: line, Err: err}
+	}
+	return task, nil
+}
+
+// readHeader reads the header and records where the known columns are.
+func (s *TypedCSVSource[In, Out]) readHeader() error {
+	header, err := s.reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return io.EOF // An empty file has no tasks.
+		}
+		return fmt.Errorf("workerpool: reading the CSV header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["data"]; !ok {
+		return fmt.Errorf(`workerpool: the CSV header has no "data" column: %q`, header)
+	}
+	s.columns = columns
+	return nil
+}
+
+// parse builds the task held in record, the index-th record after the header.
+func (s *TypedCSVSource[In, Out]) parse(record []string, index int) (TypedTask[In, Out], error) {
+	cells := make(map[string]string, len(csvColumns))
+	for _, name := range csvColumns {
+		if i, ok := s.columns[name]; ok && i < len(record) {
+			cells[name] = strings.TrimSpace(record[i])
+		}
+	}
+
+	task := TypedTask[In, Out]{ID: index, TraceID: cells["trace_id"]}
+	var err error
+	if cells["id"] != "" {
+		if task.ID, err = strconv.Atoi(cells["id"]); err != nil {
+			return task, fmt.Errorf("id: %w", err)
+		}
+	}
+	if task.Data, err = s.ParseData(cells["data"]); err != nil {
+		return task, fmt.Errorf("data: %w", err)
+	}
+	if task.Complexity, err = parseDurationCell(cells["complexity"]); err != nil {
+		return task, fmt.Errorf("complexity: %w", err)
+	}
+	if task.Timeout, err = parseDurationCell(cells["timeout"]); err != nil {
+		return task, fmt.Errorf("timeout: %w", err)
+	}
+	return task, nil
+}
+
+// parseDurationCell parses a duration cell, where an empty cell means zero.
+func parseDurationCell(cell string) (time.Duration, error) {
+	if cell == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(cell)
+}
+
+// Close closes the file opened by OpenCSVSource. It does nothing for a source
+// created from a reader.
+func (s *TypedCSVSource[In, Out]) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	closer := s.closer
+	s.closer = nil
+	return closer.Close()
+}
+
+// TypedJSONLinesSource reads tasks from JSON Lines: one JSON object per line
+// with the fields "data" (required), "id", "complexity", "timeout", "deadline"
+// and "trace_id"; other fields are ignored. Durations are strings that
+// time.ParseDuration accepts (e.g. "150ms") or numbers of nanoseconds, and the
+// deadline is an RFC 3339 time. Blank lines are skipped. Without an "id", a
+// task's ID is the index of its line among the non-blank ones, starting at 0.
+type TypedJSONLinesSource[In, Out any] struct {
+	reader  *bufio.Reader
+	closer  io.Closer // The file opened by OpenJSONLinesSource, if any.
+	line    int       // Number of lines read.
+	records int       // Number of non-blank lines read.
+}
+
+// jsonLinesTask is one line of a TypedJSONLinesSource.
+type jsonLinesTask[In any] struct {
+	ID         *int         `json:"id"`
+	Data       *In          `json:"data"`
+	Complexity jsonDuration `json:"complexity"`
+	Timeout    jsonDuration `json:"timeout"`
+	Deadline   time.Time    `json:"deadline"`
+	TraceID    string       `json:"trace_id"`
+}
+
+// jsonDuration is a time.Duration read from JSON as a string such as "150ms"
+// or as a number of nanoseconds.
+type jsonDuration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *jsonDuration) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var text string
+	if json.Unmarshal(data, &text) == nil {
+		v, err := time.ParseDuration(text)
+		*d = jsonDuration(v)
+		return err
+	}
+	var nanos int64
+	if err := json.Unmarshal(data, &nanos); err != nil {
+		return fmt.Errorf(`want a duration such as "150ms" or a number of nanoseconds, got %s`, data)
+	}
+	*d = jsonDuration(nanos)
+	return nil
+}
+
+// NewTypedJSONLinesSource creates a TypedJSONLinesSource reading r. The "data"
+// field of every line is decoded into In.
+func NewTypedJSONLinesSource[In, Out any](r io.Reader) *TypedJSONLinesSource[In, Out] {
+	return &TypedJSONLinesSource[In, Out]{reader: bufio.NewReader(r)}
+}
+
+// NewJSONLinesSource creates a TypedJSONLinesSource reading prime-checking
+// tasks from r, whose "data" fields are integers.
+func NewJSONLinesSource(r io.Reader) *TypedJSONLinesSource[int, any] {
+	return NewTypedJSONLinesSource[int, any](r)
+}
+
+// OpenJSONLinesSource opens the file at path and reads prime-checking tasks
+// from it like NewJSONLinesSource. Close closes the file.
+func OpenJSONLinesSource(path string) (*TypedJSONLinesSource[int, any], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	source := NewJSONLinesSource(file)
+	source.closer = file
+	return source, nil
+}
+
+// Next implements TaskSource.
+func (s *TypedJSONLinesSource[In, Out]) Next() (TypedTask[In, Out], error) {
+	for {
+		data, err := s.reader.ReadBytes('\n')
+		// A last line without a newline is still a line: only stop at io.EOF
+		// once there is nothing left.
+		if err != nil && (err != io.EOF || len(data) == 0) {
+			return TypedTask[In, Out]{}, err
+		}
+		s.line++
+		data = bytes.TrimSpace(data)
+		if len(data) == 0 {
+			continue
+		}
+		index := s.records
+		s.records++
+
+		var record jsonLinesTask[In]
+		if err := json.Unmarshal(data, &record); err != nil {
+			return TypedTask[In, Out]{}, &LineError{Line: s.line, Err: err}
+		}
+		if record.Data == nil {
+			return TypedTask[In, Out]{}, &LineError{Line: s.line, Err: errors.New(`missing "data"`)}
+		}
+		task := TypedTask[In, Out]{
+			ID:         index,
+			Data:       *record.Data,
+			Complexity: time.Duration(record.Complexity),
+			Timeout:    time.Duration(record.Timeout),
+			Deadline:   record.Deadline,
+			TraceID:    record.TraceID,
+		}
+		if record.ID != nil {
+			task.ID = *record.ID
+		}
+		return task, nil
+	}
+}
+
+// Close closes the file opened by OpenJSONLinesSource. It does nothing for a
+// source created from a reader.
+func (s *TypedJSONLinesSource[In, Out]) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	closer := s.closer
+	s.closer = nil
+	return closer.Close()
+}
+
+// NewStdinSource returns a source reading prime-checking tasks from standard
+// input in the given format: "csv" (see TypedCSVSource) or "jsonl" (see
+// TypedJSONLinesSource). Standard input is not closed by the source.
+func NewStdinSource(format string) (TaskSource[int, any], error) {
+	switch format {
+	case "csv":
+		return NewCSVSource(os.Stdin), nil
+	case "jsonl":
+		return NewJSONLinesSource(os.Stdin), nil
+	default:
+		return nil, fmt.Errorf("workerpool: unknown task source format %q: want csv or jsonl", format)
+	}
+}
@@ -0,0 +1,106 @@
+package exercise02workerpool_test
+
+import (
+	"context" // Used to run the producers.
+	"errors"  // Used to check the errors reported for malformed lines.
+	"strings" // Used to build the inputs.
+	"testing" // The testing package is required for tests.
+	"time"    // Used for task complexities.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// readSource runs a SourceProducer over source and returns the tasks it sent and
+// the line numbers of the malformed records it skipped.
+func readSource(t *testing.T, source exercise02workerpool.TaskSource[int, any]) ([]exercise02workerpool.Task, []int) {
+	t.Helper()
+	taskChan := make(chan exercise02workerpool.Task, 100)
+	producer := exercise02workerpool.NewSourceProducer(source, taskChan)
+	var lines []int
+	producer.OnMalformed = func(err *exercise02workerpool.LineError) {
+		lines = append(lines, err.Line)
+	}
+	if err := producer.Start(context.Background()); err != nil {
+		t.Fatalf("Start returned %v", err)
+	}
+	if producer.Malformed() != len(lines) {
+		t.Errorf("Malformed is %d, but OnMalformed was called %d times", producer.Malformed(), len(lines))
+	}
+
+	var tasks []exercise02workerpool.Task
+	for task := range taskChan { // Ends because the producer closed TaskChan.
+		tasks = append(tasks, task)
+	}
+	return tasks, lines
+}
+
+// TestCSVSource checks that the columns are found by name, that a malformed
+// record is reported with its line number and skipped, and that records without
+// an ID are numbered by position.
+func TestCSVSource(t *testing.T) {
+	input := "complexity, data ,id\n" +
+		"5ms,7919,1\n" +
+		"1ms,abc,2\n" +
+		"\n" +
+		",10,\n"
+	tasks, lines := readSource(t, exercise02workerpool.NewCSVSource(strings.NewReader(input)))
+
+	if len(lines) != 1 || lines[0] != 3 {
+		t.Errorf("malformed lines %v, want [3]", lines)
+	}
+	if len(tasks) != 2 {
+		t.Fatalf("got %d tasks, want 2", len(tasks))
+	}
+	if tasks[0].ID != 1 || tasks[0].Data != 7919 || tasks[0].Complexity != 5*time.Millisecond {
+		t.Errorf("first task is %+v, want ID 1, Data 7919 and Complexity 5ms", tasks[0])
+	}
+	if tasks[1].ID != 2 || tasks[1].Data != 10 || tasks[1].Complexity != 0 {
+		t.Errorf("second task is %+v, want ID 2 (its position), Data 10 and no Complexity", tasks[1])
+	}
+
+	// A header without a "data" column stops the source instead.
+	taskChan := make(chan exercise02workerpool.Task)
+	producer := exercise02workerpool.NewSourceProducer(exercise02workerpool.NewCSVSource(strings.NewReader("id,value\n1,2\n")), taskChan)
+	if err := producer.Start(context.Background()); err == nil {
+		t.Error("Start accepted a CSV header without a data column")
+	}
+	if _, ok := <-taskChan; ok {
+		t.Error("TaskChan is still open after the source failed")
+	}
+}
+
+// TestJSONLinesSource checks both duration notations, the default IDs, blank
+// lines, a last line without a newline, and malformed lines.
+func TestJSONLinesSource(t *testing.T) {
+	input := `{"id": 10, "data": 7, "complexity": "2ms"}` + "\n" +
+		"\n" +
+		`{"data": 8, "timeout": 1000000}` + "\n" +
+		"not json\n" +
+		`{"id": 12}` + "\n" +
+		`{"id": 13, "data": 9}`
+	source := exercise02workerpool.NewJSONLinesSource(strings.NewReader(input))
+	tasks, lines := readSource(t, source)
+
+	if len(lines) != 2 || lines[0] != 4 || lines[1] != 5 {
+		t.Errorf("malformed lines %v, want [4 5]", lines)
+	}
+	if len(tasks) != 3 {
+		t.Fatalf("got %d tasks, want 3", len(tasks))
+	}
+	if tasks[0].ID != 10 || tasks[0].Data != 7 || tasks[0].Complexity != 2*time.Millisecond {
+		t.Errorf("first task is %+v, want ID 10, Data 7 and Complexity 2ms", tasks[0])
+	}
+	if tasks[1].ID != 1 || tasks[1].Data != 8 || tasks[1].Timeout != time.Millisecond {
+		t.Errorf("second task is %+v, want ID 1 (its position), Data 8 and Timeout 1ms", tasks[1])
+	}
+	if tasks[2].ID != 13 || tasks[2].Data != 9 {
+		t.Errorf("last task is %+v, want ID 13 and Data 9", tasks[2])
+	}
+
+	// The errors keep their cause.
+	_, err := exercise02workerpool.NewJSONLinesSource(strings.NewReader(`{"data": "x"}`)).Next()
+	var lineErr *exercise02workerpool.LineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 1 {
+		t.Errorf("Next returned %v, want a LineError for line 1", err)
+	}
+}
@@ -0,0 +1,166 @@
+package exercise02workerpool
+
+import (
+	"sync"        // Package for the mutex guarding the histograms.
+	"sync/atomic" // Package for the lock-free task counters.
+	"time"        // Package for durations recorded by the histograms.
+)
+
+// DefaultBuckets are the upper bounds of the histogram buckets used by Stats.
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	2500 * time.Microsecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Histogram counts durations in buckets with fixed upper bounds.
+// Counts[i] is the number of observations d with Bounds[i-1] < d <= Bounds[i];
+// the extra last entry counts the observations above every bound.
+type Histogram struct {
+	Bounds []time.Duration // Upper bounds of the buckets, in increasing order.
+	Counts []int64         // Observations per bucket; len(Bounds)+1 entries.
+	Count  int64           // Total number of observations.
+	Sum    time.Duration   // Sum of all observations.
+}
+
+// newHistogram returns an empty histogram with the given bucket bounds.
+func newHistogram(bounds []time.Duration) Histogram {
+	return Histogram{Bounds: bounds, Counts: make([]int64, len(bounds)+1)}
+}
+
+// observe records d.
+func (h *Histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(h.Bounds) && d > h.Bounds[i] {
+		i++
+	}
+	h.Counts[i]++
+	h.Count++
+	h.Sum += d
+}
+
+// clone returns a deep copy of h, safe to hand out while h keeps changing.
+func (h Histogram) clone() Histogram {
+	h.Counts = append([]int64(nil), h.Counts...)
+	return h
+}
+
+// Mean returns the average observation, or 0 if there is none.
+func (h Histogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Quantile returns an upper estimate of the q-quantile (0 <= q <= 1): the bound
+// of the bucket in which it falls. Observations above every bound are reported
+// as the largest bound. It returns 0 if there is no observation.
+func (h Histogram) Quantile(q float64) time.Duration {
+	if h.Count == 0 || len(h.Bounds) == 0 {
+		return 0
+	}
+	rank := int64(q * float64(h.Count))
+	var seen int64
+	for i, n := range h.Counts[:len(h.Bounds)] {
+		seen += n
+		if seen > rank {
+			return h.Bounds[i]
+		}
+	}
+	return h.Bounds[len(h.Bounds)-1]
+}
+
+// Stats is a point-in-time snapshot of a pool's activity, returned by Pool.Stats.
+type Stats struct {
+	Submitted int64 // Tasks accepted from TaskChan.
+	Completed int64 // Tasks finished successfully.
+	Failed    int64 // Tasks that failed for good (after any retries).
+	Retried   int64 // Retries scheduled by the retry policy.
+	Panics    int64 // Attempts whose Processor panicked.
+	Pending   int64 // Accepted tasks not finished yet: queued, being processed or waiting for a retry.
+	InFlight  int   // Tasks a worker is processing right now.
+	Workers   int   // The configured number of workers.
+
+	Circuit         CircuitState // State of the pool's circuit breaker; always CircuitClosed without one.
+	CircuitRejected int64        // Attempts the circuit breaker rejected with ErrCircuitOpen.
+
+	WorkerBusy map[int]time.Duration // Time each worker (by ID, including retired ones) spent processing.
+	QueueWait  Histogram             // Time from being accepted (or due for a retry) to being taken by a worker.
+	Processing Histogram             // Time a worker spent processing each attempt.
+}
+
+// poolMetrics gathers the counters and histograms behind Stats. The counters
+// are atomic; the histograms are guarded by mu.
+type poolMetrics struct {
+	submitted atomic.Int64
+	completed atomic.Int64
+	failed    atomic.Int64
+	retried   atomic.Int64
+
+	mu         sync.Mutex
+	queueWait  Histogram
+	processing Histogram
+}
+
+// newPoolMetrics returns empty metrics using DefaultBuckets.
+func newPoolMetrics() *poolMetrics {
+	return &poolMetrics{
+		queueWait:  newHistogram(DefaultBuckets),
+		processing: newHistogram(DefaultBuckets),
+	}
+}
+
+// observeAttempt records the timings of one processing attempt.
+func (m *poolMetrics) observeAttempt(queueWait, processing time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queueWait.observe(queueWait)
+	m.processing.observe(processing)
+}
+
+// Stats returns a snapshot of the pool's counters, histograms and per-worker
+// busy time. It is safe to call concurrently with a running pool, from any goroutine.
+func (p *TypedPool[In, Out]) Stats() Stats {
+	s := Stats{
+		Submitted: p.metrics.submitted.Load(),
+		Completed: p.metrics.completed.Load(),
+		Failed:    p.metrics.failed.Load(),
+		Retried:   p.metrics.retried.Load(),
+		Panics:    p.panics.Load(),
+		Pending:   p.outstanding.Load(),
+
+		Circuit:         p.options.breaker.State(),
+		CircuitRejected: p.options.breaker.Rejected(),
+	}
+
+	p.metrics.mu.Lock()
+	s.QueueWait = p.metrics.queueWait.clone()
+	s.Processing = p.metrics.processing.clone()
+	p.metrics.mu.Unlock()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	s.Workers = p.workerCount
+	s.WorkerBusy = make(map[int]time.Duration, len(p.exitedBusy)+len(p.live))
+	for id, busy := range p.exitedBusy {
+		s.WorkerBusy[id] = busy
+	}
+	for _, w := range p.live {
+		s.WorkerBusy[w.ID] = w.BusyTime()
+		if w.busySince.Load() != 0 {
+			s.InFlight++
+		}
+	}
+	return s
+}
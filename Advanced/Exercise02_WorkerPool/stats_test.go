@@ -0,0 +1,80 @@
+package exercise02workerpool_test
+
+import (
+	"context" // Used to start the pool.
+	"errors"  // Used to make some tasks fail.
+	"testing" // The testing package is required for tests.
+	"time"    // Used for processing delays and histogram checks.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// TestPoolStats reads Stats while the pool runs (exercising it under the race
+// detector) and checks the final snapshot.
+func TestPoolStats(t *testing.T) {
+	errFail := errors.New("failure")
+	proc := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		time.Sleep(2 * time.Millisecond)
+		if task.ID%5 == 0 {
+			return nil, errFail
+		}
+		return nil, nil
+	})
+
+	pool := exercise02workerpool.NewPool(3, proc, exercise02workerpool.WithRetryPolicy(exercise02workerpool.RetryPolicy{
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+	}))
+	pool.Start(context.Background())
+
+	go func() {
+		for i := 0; i < 50; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i}
+		}
+		close(pool.TaskChan)
+	}()
+
+	stop := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				if s := pool.Stats(); s.InFlight > s.Workers {
+					t.Errorf("InFlight %d exceeds Workers %d", s.InFlight, s.Workers)
+				}
+			}
+		}
+	}()
+
+	collect(t, pool.ResultChan)
+	close(stop)
+	<-polled
+
+	s := pool.Stats()
+	if s.Submitted != 50 || s.Completed != 40 || s.Failed != 10 || s.Retried != 10 || s.Pending != 0 {
+		t.Errorf("got counters %+v", s)
+	}
+	if s.Processing.Count != 60 || s.QueueWait.Count != 60 {
+		t.Errorf("got %d processing and %d queue-wait observations, want 60 each", s.Processing.Count, s.QueueWait.Count)
+	}
+	if mean := s.Processing.Mean(); mean < 2*time.Millisecond {
+		t.Errorf("mean processing time %v, want at least 2ms", mean)
+	}
+	if q := s.Processing.Quantile(0.5); q < 2*time.Millisecond {
+		t.Errorf("median processing time %v, want at least 2ms", q)
+	}
+	if len(s.WorkerBusy) != 3 {
+		t.Errorf("got busy time for %d workers, want 3", len(s.WorkerBusy))
+	}
+	var busy time.Duration
+	for _, d := range s.WorkerBusy {
+		busy += d
+	}
+	if busy < 120*time.Millisecond {
+		t.Errorf("total busy time %v, want at least 120ms", busy)
+	}
+}
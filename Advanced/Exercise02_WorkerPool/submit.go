@@ -0,0 +1,204 @@
+package exercise02workerpool
+
+import (
+	"context" // Package for cancellation signals that abandon a submission.
+	"errors"  // Package for defining the sentinel error of a closed input.
+	"sync"    // Package for the mutex and WaitGroup guarding the closing of TaskChan.
+	"time"    // Package for the time a submitted task starts its enqueue span.
+)
+
+// ErrInputClosed is returned by Submit once the pool's input has been closed,
+// by CloseInput or because every producer registered with AddProducer is done.
+var ErrInputClosed = errors.New("workerpool: pool input is closed")
+
+// ErrQueueFull is returned by TrySubmit when the pool cannot take a task at
+// once, and by SubmitWithTimeout when it could not take it in time.
+var ErrQueueFull = errors.New("workerpool: task queue is full")
+
+// poolInput guards the closing of TaskChan, so that any number of goroutines
+// can submit tasks concurrently and the channel is closed exactly once, after
+// the last send on it has completed.
+type poolInput struct {
+	mu        sync.Mutex     // Protects closed and producers.
+	closed    bool           // Whether CloseInput has been called.
+	closing   chan struct{}  // Closed by CloseInput, releasing the submissions still waiting.
+	sending   sync.WaitGroup // Counts the submissions that may still send on TaskChan.
+	producers int            // Producers registered with AddProducer that are not done yet.
+}
+
+// Submit sends task to the pool, waiting until the pool can take it (a free
+// worker, or room in the queue set WithQueueCapacity), like a send on TaskChan.
+// Any number of goroutines may call Submit concurrently. It returns
+// ErrPoolStopped once the run is over (finished or cancelled), ErrInputClosed
+// once the input is closed, also to a call still waiting when that happens, and
+// ctx.Err() if ctx is done first. The task was accepted if and only if the error
+// is nil; like any queued task, it is still dropped if the run is then cancelled.
+//
+// Tasks submitted before Start wait for it. A pool fed through Submit must not
+// have TaskChan closed directly: CloseInput, or the last producer registered
+// with AddProducer, closes it once every submission has returned.
+func (p *TypedPool[In, Out]) Submit(ctx context.Context, task TypedTask[In, Out]) error {
+	_, err := p.send(ctx, task, true)
+	return err
+}
+
+// TrySubmit sends task to the pool only if it can take it right away, and
+// returns ErrQueueFull otherwise, so a latency-sensitive caller (e.g. a request
+// handler) can shed load instead of waiting. Without WithQueueCapacity that
+// means the dispatcher must be waiting for a task at that very moment. It also
+// returns ErrInputClosed or ErrPoolStopped as Submit does.
+func (p *TypedPool[In, Out]) TrySubmit(task TypedTask[In, Out]) error {
+	accepted, err := p.send(context.Background(), task, false)
+	if err == nil && !accepted {
+		return ErrQueueFull
+	}
+	return err
+}
+
+// SubmitWithTimeout is Submit giving up after timeout: it returns ErrQueueFull
+// if the pool did not take the task in time, or ctx.Err() if ctx is done first.
+func (p *TypedPool[In, Out]) SubmitWithTimeout(ctx context.Context, task TypedTask[In, Out], timeout time.Duration) error {
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, ErrQueueFull)
+	defer cancel()
+	err := p.Submit(ctx, task)
+	if err != nil && err == ctx.Err() {
+		// The cause tells the timeout apart from the caller's own ctx ending.
+		return context.Cause(ctx)
+	}
+	return err
+}
+
+// send submits task to TaskChan. Without wait it gives up at once if the pool
+// cannot take the task, returning false and no error. The sender is counted in
+// p.input.sending while it may still send, so CloseInput never closes TaskChan
+// under it.
+//
+// With WithQueueCapacity, a send to TaskChan can succeed after the dispatcher
+// has returned, as long as the buffer has room, and a select picks at random
+// among ready cases. So p.stopped is checked before sending, and again after a
+// buffered send, which then reports ErrPoolStopped: nobody will read the task.
+func (p *TypedPool[In, Out]) send(ctx context.Context, task TypedTask[In, Out], wait bool) (bool, error) {
+	in := &p.input
+	in.mu.Lock()
+	if p.isStopped() {
+		in.mu.Unlock()
+		return false, ErrPoolStopped
+	}
+	if in.closed {
+		in.mu.Unlock()
+		return false, ErrInputClosed
+	}
+	in.sending.Add(1)
+	in.mu.Unlock()
+	defer in.sending.Done()
+
+	if task.sentAt.IsZero() {
+		task.sentAt = time.Now() // Starts the task's enqueue span when tracing is enabled.
+	}
+
+	if !wait {
+		select {
+		case p.TaskChan <- task:
+			return p.sent()
+		default:
+			return false, nil
+		}
+	}
+	select {
+	case p.TaskChan <- task:
+		return p.sent()
+	case <-in.closing:
+		return false, ErrInputClosed
+	case <-p.stopped:
+		return false, ErrPoolStopped
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// sent reports the outcome of a send to TaskChan that went through. An
+// unbuffered send only completes when the dispatcher receives the task, but a
+// buffered one may have landed after the dispatcher returned.
+func (p *TypedPool[In, Out]) sent() (bool, error) {
+	if cap(p.TaskChan) > 0 && p.isStopped() {
+		return false, ErrPoolStopped
+	}
+	return true, nil
+}
+
+// isStopped reports whether the dispatcher has returned, so that nothing will
+// read TaskChan anymore.
+func (p *TypedPool[In, Out]) isStopped() bool {
+	select {
+	case <-p.stopped:
+		return true
+	default:
+		return false
+	}
+}
+
+// CloseInput closes the pool's input: Submit fails with ErrInputClosed from
+// then on, and TaskChan is closed once the submissions in progress have
+// returned, so the pool finishes the tasks it accepted and closes ResultChan.
+// Submissions still waiting for a worker are abandoned with ErrInputClosed.
+// It is safe to call more than once and from several goroutines.
+func (p *TypedPool[In, Out]) CloseInput() {
+	in := &p.input
+	in.mu.Lock()
+	if in.closed {
+		in.mu.Unlock()
+		return
+	}
+	in.closed = true
+	close(in.closing)
+	in.mu.Unlock()
+
+	// No new submission can start now; wait for the ones already sending.
+	in.sending.Wait()
+	close(p.TaskChan)
+}
+
+// TypedPoolInput is one producer's share of a pool's input, returned by
+// AddProducer. The producer submits its tasks through it and calls Done when it
+// has no more; the pool's input is closed when the last producer is done.
+type TypedPoolInput[In, Out any] struct {
+	pool *TypedPool[In, Out]
+	once sync.Once
+}
+
+// PoolInput is a TypedPoolInput for the prime-checking Task.
+type PoolInput = TypedPoolInput[int, any]
+
+// AddProducer registers a producer that will feed the pool, and returns its
+// input. The pool's input is closed once every registered producer has called
+// Done, so register all of them before starting any: like sync.WaitGroup.Add,
+// a registration that comes after the others are done is too late, and its
+// submissions fail with ErrInputClosed. Setting the Input field of a Producer,
+// TypedProducer or SourceProducer makes it submit through the input.
+func (p *TypedPool[In, Out]) AddProducer() *TypedPoolInput[In, Out] {
+	p.input.mu.Lock()
+	defer p.input.mu.Unlock()
+	p.input.producers++
+	return &TypedPoolInput[In, Out]{pool: p}
+}
+
+// Submit sends task to the pool, exactly as TypedPool.Submit does.
+func (in *TypedPoolInput[In, Out]) Submit(ctx context.Context, task TypedTask[In, Out]) error {
+	return in.pool.Submit(ctx, task)
+}
+
+// Done reports that the producer will submit no more tasks. The last producer
+// to be done closes the pool's input, as CloseInput does. Calling it again
+// has no effect.
+func (in *TypedPoolInput[In, Out]) Done() {
+	in.once.Do(func() {
+		input := &in.pool.input
+		input.mu.Lock()
+		input.producers--
+		last := input.producers == 0
+		input.mu.Unlock()
+		if last {
+			in.pool.CloseInput()
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package exercise02workerpool_test
+
+import (
+	"context" // Used to run the pool and the producers.
+	"errors"  // Used to check the errors returned by Submit.
+	"strings" // Used to read a task source from a string.
+	"sync"    // Used to run the submitting goroutines concurrently.
+	"testing" // The testing package is required for tests.
+	"time"    // Used for test timeouts.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// TestPoolSubmit checks that several goroutines can submit to one pool at once,
+// and that CloseInput ends the run once.
+func TestPoolSubmit(t *testing.T) {
+	pool := exercise02workerpool.NewPool(4, nil)
+	pool.Start(context.Background())
+
+	var wg sync.WaitGroup
+	for g := 0; g < 5; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				task := exercise02workerpool.Task{ID: g*20 + i, Data: i}
+				if err := pool.Submit(context.Background(), task); err != nil {
+					t.Errorf("Submit returned %v", err)
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		pool.CloseInput()
+		pool.CloseInput() // Closing twice is harmless.
+	}()
+
+	seen := make(map[int]bool)
+	for task := range pool.ResultChan {
+		seen[task.ID] = true
+	}
+	if len(seen) != 100 {
+		t.Errorf("received %d distinct results, want 100", len(seen))
+	}
+	if err := pool.Submit(context.Background(), exercise02workerpool.Task{}); !errors.Is(err, exercise02workerpool.ErrPoolStopped) {
+		t.Errorf("Submit after the run returned %v, want ErrPoolStopped", err)
+	}
+}
+
+// TestPoolAddProducer checks that producers registered with AddProducer feed
+// one pool together, and that its input is closed when the last one is done.
+func TestPoolAddProducer(t *testing.T) {
+	pool := exercise02workerpool.NewPool(4, nil)
+
+	// Every producer is registered before any of them starts.
+	generated := exercise02workerpool.NewProducerWithSeed(30, nil, 1)
+	generated.Input = pool.AddProducer()
+	generated.MaxComplexity = generated.MinComplexity
+	source := exercise02workerpool.NewSourceProducer(exercise02workerpool.NewCSVSource(
+		strings.NewReader("id,data\n100,7\n101,8\n")), nil)
+	source.Input = pool.AddProducer()
+	manual := pool.AddProducer()
+
+	ctx := context.Background()
+	pool.Start(ctx)
+	go generated.Start(ctx)
+	go source.Start(ctx)
+	go func() {
+		defer manual.Done()
+		for i := 200; i < 210; i++ {
+			manual.Submit(ctx, exercise02workerpool.Task{ID: i, Data: i})
+		}
+	}()
+
+	received := 0
+	timeout := time.After(10 * time.Second)
+	for done := false; !done; {
+		select {
+		case _, ok := <-pool.ResultChan:
+			if !ok {
+				done = true
+				break
+			}
+			received++
+		case <-timeout:
+			t.Fatalf("the input was not closed after %d results", received)
+		}
+	}
+	if received != 42 {
+		t.Errorf("received %d results, want 42", received)
+	}
+}
+
+// TestPoolTrySubmit checks that TrySubmit and SubmitWithTimeout reject tasks
+// with ErrQueueFull while the workers are busy and the queue is full.
+func TestPoolTrySubmit(t *testing.T) {
+	release := make(chan struct{})
+	blocked := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		<-release
+		return nil, nil
+	})
+	pool := exercise02workerpool.NewPool(1, blocked, exercise02workerpool.WithQueueCapacity(2))
+	ctx := context.Background()
+	pool.Start(ctx)
+
+	// One task is processed, one waits for the worker in the dispatcher and the
+	// last two fill the queue: the fourth Submit only returns once the first two
+	// have left it.
+	for i := 0; i < 4; i++ {
+		if err := pool.Submit(ctx, exercise02workerpool.Task{ID: i}); err != nil {
+			t.Fatalf("Submit returned %v", err)
+		}
+	}
+	if err := pool.TrySubmit(exercise02workerpool.Task{ID: 4}); !errors.Is(err, exercise02workerpool.ErrQueueFull) {
+		t.Errorf("TrySubmit on a full queue returned %v, want ErrQueueFull", err)
+	}
+	if err := pool.SubmitWithTimeout(ctx, exercise02workerpool.Task{ID: 4}, 10*time.Millisecond); !errors.Is(err, exercise02workerpool.ErrQueueFull) {
+		t.Errorf("SubmitWithTimeout on a full queue returned %v, want ErrQueueFull", err)
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := pool.SubmitWithTimeout(cancelled, exercise02workerpool.Task{ID: 4}, time.Minute); !errors.Is(err, context.Canceled) {
+		t.Errorf("SubmitWithTimeout with a cancelled context returned %v, want context.Canceled", err)
+	}
+
+	// The run is still busy, so the input being closed is what rejects the task.
+	pool.CloseInput()
+	if err := pool.TrySubmit(exercise02workerpool.Task{}); !errors.Is(err, exercise02workerpool.ErrInputClosed) {
+		t.Errorf("TrySubmit after CloseInput returned %v, want ErrInputClosed", err)
+	}
+
+	close(release)
+	received := 0
+	for range pool.ResultChan {
+		received++
+	}
+	if received != 4 {
+		t.Errorf("received %d results, want the 4 accepted tasks", received)
+	}
+}
+
+// TestPoolSubmitAfterRun checks that a pool with a queue rejects every task
+// once its run is over, cancelled or finished, although the queue has room.
+func TestPoolSubmitAfterRun(t *testing.T) {
+	// A select picks at random among ready cases, so repeat the runs.
+	for i := 0; i < 100; i++ {
+		for _, cancelled := range []bool{true, false} {
+			pool := exercise02workerpool.NewPool(2, nil, exercise02workerpool.WithQueueCapacity(4))
+			ctx, cancel := context.WithCancel(context.Background())
+			pool.Start(ctx)
+			if cancelled {
+				cancel()
+			} else {
+				pool.CloseInput()
+			}
+			for range pool.ResultChan {
+			}
+			cancel()
+
+			task := exercise02workerpool.Task{ID: i}
+			if err := pool.Submit(context.Background(), task); !errors.Is(err, exercise02workerpool.ErrPoolStopped) {
+				t.Fatalf("Submit after the run (cancelled %v) returned %v, want ErrPoolStopped", cancelled, err)
+			}
+			if err := pool.TrySubmit(task); !errors.Is(err, exercise02workerpool.ErrPoolStopped) {
+				t.Fatalf("TrySubmit after the run (cancelled %v) returned %v, want ErrPoolStopped", cancelled, err)
+			}
+			if _, err := pool.SubmitFuture(context.Background(), task); !errors.Is(err, exercise02workerpool.ErrPoolStopped) {
+				t.Fatalf("SubmitFuture after the run (cancelled %v) returned %v, want ErrPoolStopped", cancelled, err)
+			}
+		}
+	}
+}
@@ -2,16 +2,52 @@ package exercise02workerpool
 
 import "time"
 
-// Task represents a unit of work to be processed within the worker pool.
+// TypedTask represents a unit of work to be processed within the worker pool.
 // It holds all necessary information for a worker to perform its computation.
-type Task struct {
+// In is the type of the input data and Out the type of the result, so that
+// consumers receive a typed Result instead of having to type-assert it.
+type TypedTask[In, Out any] struct {
 	ID         int           // Unique identifier for the task.
-	Data       int           // The input data for the CPU-intensive calculation (e.g., number to check for primality).
+	Data       In            // The input data for the calculation (e.g., number to check for primality).
 	Complexity time.Duration // Simulated duration for processing this specific task.
-	Result     any           // Stores the outcome of the task's processing (e.g., boolean for isPrime). 'any' type allows flexibility for different task results.
+	Deadline   time.Time     // Optional absolute deadline for processing. Zero means none.
+	Timeout    time.Duration // Optional processing time limit, used when Deadline is zero. Zero means none.
+	Result     Out           // Stores the outcome of the task's processing (e.g., boolean for isPrime).
 	Err        error         // Stores any error that occurred during task processing. Nil if successful.
+	Attempts   int           // Number of times the task has been processed, including retries.
+	Errors     []error       // The error of every failed attempt, oldest first.
+	TraceID    string        // Identifies the task's spans when tracing is enabled; generated by the pool if empty.
+
+	// Timestamps of the current attempt, used by the pool's statistics and tracing.
+	sentAt      time.Time // When the producer started sending the task to TaskChan.
+	queuedAt    time.Time // When the pool queued the attempt for a worker.
+	takenAt     time.Time // When a worker took the attempt.
+	processedAt time.Time // When the worker finished processing the attempt.
+	finishedAt  time.Time // When the pool finished the task for good.
+	workerID    int       // The worker that took the attempt.
+	spans       []Span    // Spans recorded so far, exported once the task is finished.
+
+	future *TypedFuture[In, Out] // Resolved with the task instead of delivering it, if set by SubmitFuture.
 }
 
+// Reset returns a copy of the task with its outcome cleared (Result, Err,
+// Attempts, Errors and recorded spans), ready to be submitted again, e.g. after
+// inspecting it on the pool's dead-letter channel. The input fields and TraceID
+// are kept unchanged, so the new spans join the same trace.
+func (t TypedTask[In, Out]) Reset() TypedTask[In, Out] {
+	var zero Out
+	t.Result = zero
+	t.Err = nil
+	t.Attempts = 0
+	t.Errors = nil
+	t.spans = nil
+	return t
+}
+
+// Task is the task type used by the original prime-checking pool: an int input
+// and an untyped Result. It is kept so existing code continues to compile.
+type Task = TypedTask[int, any]
+
 // isPrime checks if a given number is prime.
 // This function serves as the CPU-intensive calculation that workers will perform.
 // It uses an optimized algorithm for primality testing (checking divisibility only by 6k ± 1).
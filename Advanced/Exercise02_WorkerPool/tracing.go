@@ -0,0 +1,160 @@
+package exercise02workerpool
+
+import (
+	"bufio"         // Package for buffering the spans written to a file.
+	"crypto/rand"   // Package for generating random trace IDs.
+	"encoding/hex"  // Package for formatting trace IDs.
+	"encoding/json" // Package for encoding spans as JSON lines.
+	"os"            // Package for creating the file the spans are written to.
+	"sync"          // Package for the mutexes guarding the exporters.
+	"time"          // Package for span timestamps.
+)
+
+// Names of the spans recorded for every task of a pool created WithTracing.
+const (
+	SpanEnqueue    = "enqueue"    // From the producer starting to send the task until the pool accepted it from TaskChan.
+	SpanDequeue    = "dequeue"    // From the pool queuing an attempt until a worker took it; recorded per attempt.
+	SpanProcessing = "processing" // The worker running the Processor; recorded per attempt.
+	SpanDelivery   = "delivery"   // From the task being finished until ResultChan (or DeadLetterChan) accepted it.
+)
+
+// Span is a timed step in the life of a task. All the spans of a task share its
+// TraceID, so the time the task spent waiting in TaskChan, for a worker, in
+// processing and for room in ResultChan can be told apart.
+type Span struct {
+	TraceID  string    `json:"trace_id"`        // The trace of the task, as in Task.TraceID.
+	Name     string    `json:"name"`            // One of SpanEnqueue, SpanDequeue, SpanProcessing or SpanDelivery.
+	TaskID   int       `json:"task_id"`         // The ID of the task.
+	Attempt  int       `json:"attempt"`         // The attempt the span belongs to; 0 for enqueue.
+	WorkerID int       `json:"worker_id"`       // The worker that processed the attempt; -1 when no worker was involved.
+	Start    time.Time `json:"start"`           // When the step started.
+	End      time.Time `json:"end"`             // When the step ended.
+	Error    string    `json:"error,omitempty"` // The error of a failed processing span, or of the task on delivery.
+}
+
+// Duration returns how long the step took.
+func (s Span) Duration() time.Duration {
+	return s.End.Sub(s.Start)
+}
+
+// SpanExporter receives the spans of finished tasks. ExportSpans is called once
+// per task, with all its spans in order, from the pool's collector goroutine; a
+// slow exporter therefore slows down the delivery of results.
+type SpanExporter interface {
+	ExportSpans(spans []Span) error
+}
+
+// InMemoryExporter keeps every exported span in memory, e.g. for tests or for
+// inspecting a run after it finished. It is safe for concurrent use.
+type InMemoryExporter struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+// NewInMemoryExporter returns an empty InMemoryExporter.
+func NewInMemoryExporter() *InMemoryExporter {
+	return &InMemoryExporter{}
+}
+
+// ExportSpans stores spans.
+func (e *InMemoryExporter) ExportSpans(spans []Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, spans...)
+	return nil
+}
+
+// Spans returns a copy of the spans exported so far, in export order.
+func (e *InMemoryExporter) Spans() []Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Span(nil), e.spans...)
+}
+
+// JSONFileExporter writes every span as a line of JSON to a file, so traces can
+// be inspected offline. Close must be called to flush the last spans.
+type JSONFileExporter struct {
+	mu   sync.Mutex
+	file *os.File
+	buf  *bufio.Writer
+	enc  *json.Encoder
+}
+
+// NewJSONFileExporter creates (or truncates) the file at path and returns an
+// exporter writing to it.
+func NewJSONFileExporter(path string) (*JSONFileExporter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(file)
+	return &JSONFileExporter{file: file, buf: buf, enc: json.NewEncoder(buf)}, nil
+}
+
+// ExportSpans writes spans to the file, one JSON object per line.
+func (e *JSONFileExporter) ExportSpans(spans []Span) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, span := range spans {
+		if err := e.enc.Encode(span); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close flushes the buffered spans and closes the file.
+func (e *JSONFileExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if err := e.buf.Flush(); err != nil {
+		e.file.Close()
+		return err
+	}
+	return e.file.Close()
+}
+
+// newTraceID returns a random 128-bit trace ID in hex, the W3C Trace Context format.
+func newTraceID() string {
+	var id [16]byte
+	rand.Read(id[:]) // Never returns an error.
+	return hex.EncodeToString(id[:])
+}
+
+// errorString returns err's message, or "" if err is nil.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// traceAccepted starts the trace of a task the dispatcher has just accepted
+// from TaskChan and records its enqueue span.
+func (t *TypedTask[In, Out]) traceAccepted(now time.Time) {
+	if t.TraceID == "" {
+		t.TraceID = newTraceID()
+	}
+	start := t.sentAt
+	if start.IsZero() {
+		start = now // Sent by code that did not record when it started sending.
+	}
+	t.spans = append(t.spans, Span{TraceID: t.TraceID, Name: SpanEnqueue, TaskID: t.ID, WorkerID: -1, Start: start, End: now})
+}
+
+// traceAttempt records the dequeue and processing spans of the attempt a worker
+// has just finished.
+func (t *TypedTask[In, Out]) traceAttempt() {
+	t.spans = append(t.spans,
+		Span{TraceID: t.TraceID, Name: SpanDequeue, TaskID: t.ID, Attempt: t.Attempts, WorkerID: t.workerID, Start: t.queuedAt, End: t.takenAt},
+		Span{TraceID: t.TraceID, Name: SpanProcessing, TaskID: t.ID, Attempt: t.Attempts, WorkerID: t.workerID, Start: t.takenAt, End: t.processedAt, Error: errorString(t.Err)},
+	)
+}
+
+// deliveredSpans returns the task's spans followed by its delivery span. The
+// result never shares memory with t.spans: by then the receiver owns a copy of
+// the task, and may even send it to the pool again.
+func (t *TypedTask[In, Out]) deliveredSpans(start, end time.Time) []Span {
+	spans := t.spans[:len(t.spans):len(t.spans)]
+	return append(spans, Span{TraceID: t.TraceID, Name: SpanDelivery, TaskID: t.ID, Attempt: t.Attempts, WorkerID: -1, Start: start, End: end, Error: errorString(t.Err)})
+}
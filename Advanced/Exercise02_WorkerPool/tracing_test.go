@@ -0,0 +1,108 @@
+package exercise02workerpool_test
+
+import (
+	"bufio"         // Used to read the JSON lines back.
+	"context"       // Used to start the pool.
+	"encoding/json" // Used to decode the exported spans.
+	"errors"        // Used to make an attempt fail.
+	"os"            // Used to open the exported file.
+	"path/filepath" // Used to build the path of the exported file.
+	"slices"        // Used to compare span names.
+	"testing"       // The testing package is required for tests.
+	"time"          // Used for processing delays and retry backoff.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// runTraced runs three tasks through a traced pool; task 1 fails its first attempt and is retried.
+func runTraced(t *testing.T, exporter exercise02workerpool.SpanExporter) {
+	t.Helper()
+	proc := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		time.Sleep(time.Millisecond)
+		if task.ID == 1 && task.Attempts == 1 {
+			return nil, errors.New("flaky")
+		}
+		return nil, nil
+	})
+	pool := exercise02workerpool.NewPool(2, proc,
+		exercise02workerpool.WithTracing(exporter),
+		exercise02workerpool.WithRetryPolicy(exercise02workerpool.RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}))
+	pool.Start(context.Background())
+
+	producer := exercise02workerpool.NewProducer(3, pool.TaskChan)
+	go producer.Start(context.Background())
+	for _, task := range collect(t, pool.ResultChan) {
+		if task.TraceID == "" {
+			t.Errorf("task %d has no TraceID", task.ID)
+		}
+	}
+}
+
+// TestPoolTracing checks the spans recorded for every task, including a retried one.
+func TestPoolTracing(t *testing.T) {
+	exporter := exercise02workerpool.NewInMemoryExporter()
+	runTraced(t, exporter)
+
+	names := make(map[int][]string)
+	traces := make(map[int]string)
+	for _, span := range exporter.Spans() {
+		names[span.TaskID] = append(names[span.TaskID], span.Name)
+		if id, ok := traces[span.TaskID]; ok && id != span.TraceID {
+			t.Errorf("task %d has spans in traces %s and %s", span.TaskID, id, span.TraceID)
+		}
+		traces[span.TaskID] = span.TraceID
+		if span.End.Before(span.Start) {
+			t.Errorf("span %s of task %d ends before it starts", span.Name, span.TaskID)
+		}
+		if span.Name == exercise02workerpool.SpanProcessing && span.Duration() < time.Millisecond {
+			t.Errorf("processing span of task %d lasted %v, want at least 1ms", span.TaskID, span.Duration())
+		}
+	}
+
+	single := []string{"enqueue", "dequeue", "processing", "delivery"}
+	retried := []string{"enqueue", "dequeue", "processing", "dequeue", "processing", "delivery"}
+	for id := 0; id < 3; id++ {
+		want := single
+		if id == 1 {
+			want = retried
+		}
+		if got := names[id]; !slices.Equal(got, want) {
+			t.Errorf("task %d: got spans %v, want %v", id, got, want)
+		}
+	}
+}
+
+// TestJSONFileExporter checks that the exported file holds one decodable span per line.
+func TestJSONFileExporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spans.jsonl")
+	exporter, err := exercise02workerpool.NewJSONFileExporter(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runTraced(t, exporter)
+	if err := exporter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var span exercise02workerpool.Span
+		if err := json.Unmarshal(scanner.Bytes(), &span); err != nil {
+			t.Fatalf("line %d: %v", lines+1, err)
+		}
+		if span.TraceID == "" || span.Name == "" {
+			t.Errorf("line %d: incomplete span %+v", lines+1, span)
+		}
+		lines++
+	}
+	if lines != 14 {
+		t.Errorf("got %d spans, want 14", lines)
+	}
+}
@@ -0,0 +1,374 @@
+package exercise02workerpool
+
+import (
+	"bufio"         // Package for reading the log line by line.
+	"bytes"         // Package for detecting a torn last line.
+	"encoding/json" // Package for encoding the records as JSON lines.
+	"fmt"           // Package for reporting malformed records with their line number.
+	"io"            // Package for reading the existing log.
+	"os"            // Package for the log file and its replacement on compaction.
+	"path/filepath" // Package for syncing the directory after a compaction.
+	"sort"          // Package for replaying and compacting tasks in ID order.
+	"sync"          // Package for the mutex guarding the log.
+	"time"          // Package for the task fields recorded and the sync interval.
+)
+
+// SyncPolicy decides when a WAL forces its records to stable storage with fsync.
+// Records are always handed to the operating system as soon as they are written,
+// so they survive a crash of the process; fsync protects them against a crash of
+// the machine. Any positive value fsyncs at most once per that duration, from a
+// background goroutine, and any negative value is the same as SyncNever.
+type SyncPolicy time.Duration
+
+const (
+	SyncAlways SyncPolicy = 0  // Fsync after every record: slowest, and nothing written is ever lost.
+	SyncNever  SyncPolicy = -1 // Never fsync; the operating system writes the records back when it sees fit.
+)
+
+// compactThreshold is the number of records made obsolete by completions above
+// which a WAL compacts itself, as long as they outnumber the pending tasks.
+const compactThreshold = 10000
+
+// WAL is a write-ahead log of the tasks a pool has accepted and finished. A pool
+// created WithWAL records every task it accepts and every task it delivers, so
+// that after a crash PendingTasks returns exactly the tasks that never finished,
+// ready to be sent to a new pool. The log is a file of JSON lines; it compacts
+// itself as completions accumulate, and Compact can be called at any time.
+type WAL struct {
+	mu      sync.Mutex
+	path    string
+	file    *os.File
+	policy  SyncPolicy
+	pending map[int]json.RawMessage // Encoded input of every task submitted and not completed, by ID.
+	nextID  int                     // One more than the highest task ID ever recorded.
+	dead    int                     // Records made obsolete by completions since the last compaction.
+	dirty   bool                    // Whether records were written since the last fsync.
+	closed  bool
+	stop    chan struct{} // Closed by Close to stop the periodic sync.
+	stopped chan struct{} // Closed once the periodic sync has returned.
+}
+
+// walRecord is one line of the log.
+type walRecord struct {
+	Op   string          `json:"op"`             // "submit", "complete" or "next".
+	ID   int             `json:"id"`             // The task ID, or the next free ID for "next".
+	Task json.RawMessage `json:"task,omitempty"` // The task's input, for "submit".
+}
+
+// walTask holds the input fields of a task, which are all a replay needs.
+type walTask[In any] struct {
+	ID         int           `json:"id"`
+	Data       In            `json:"data"`
+	Complexity time.Duration `json:"complexity,omitempty"`
+	Deadline   time.Time     `json:"deadline,omitzero"`
+	Timeout    time.Duration `json:"timeout,omitempty"`
+	TraceID    string        `json:"trace_id,omitempty"`
+}
+
+// OpenWAL opens the log at path, creating it if it does not exist, and loads the
+// tasks it records as pending. A last line cut short by a crash is discarded;
+// any other malformed line is an error.
+func OpenWAL(path string, policy SyncPolicy) (*WAL, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	// Every negative policy means never: Close compares against SyncNever.
+	policy = max(policy, SyncNever)
+	w := &WAL{path: path, file: file, policy: policy, pending: make(map[int]json.RawMessage)}
+	if err := w.load(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("workerpool: reading %s: %w", path, err)
+	}
+
+	if policy > 0 {
+		w.stop = make(chan struct{})
+		w.stopped = make(chan struct{})
+		go w.syncPeriodically(time.Duration(policy))
+	}
+	return w, nil
+}
+
+// load replays the records of the file and leaves it positioned for appending.
+func (w *WAL) load() error {
+	reader := bufio.NewReader(w.file)
+	var offset int64 // End of the last complete line.
+	for line := 1; ; line++ {
+		data, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// A line without its newline was cut short by a crash: drop it so
+			// the next record starts on a line of its own.
+			if len(bytes.TrimSpace(data)) > 0 {
+				if err := w.file.Truncate(offset); err != nil {
+					return err
+				}
+			}
+			_, err := w.file.Seek(offset, io.SeekStart)
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		offset += int64(len(data))
+
+		var record walRecord
+		if err := json.Unmarshal(data, &record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+		if err := w.apply(record); err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+	}
+}
+
+// apply updates the in-memory state with record. The caller must hold w.mu,
+// except while loading.
+func (w *WAL) apply(record walRecord) error {
+	switch record.Op {
+	case "submit":
+		if _, ok := w.pending[record.ID]; ok {
+			w.dead++ // A resubmission replaces the earlier record.
+		}
+		w.pending[record.ID] = record.Task
+		w.nextID = max(w.nextID, record.ID+1)
+	case "complete":
+		if _, ok := w.pending[record.ID]; ok {
+			delete(w.pending, record.ID)
+			w.dead += 2 // Both the submission and the completion are now obsolete.
+		}
+	case "next":
+		w.nextID = max(w.nextID, record.ID)
+	default:
+		return fmt.Errorf("unknown operation %q", record.Op)
+	}
+	return nil
+}
+
+// NextID returns one more than the highest task ID the log has ever recorded,
+// i.e. the ID a producer should start from to continue an interrupted run.
+func (w *WAL) NextID() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.nextID
+}
+
+// Len returns the number of pending tasks: submitted but not completed.
+func (w *WAL) Len() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.pending)
+}
+
+// PendingTasks returns the tasks recorded in w that have not been completed, in
+// ID order, with their input fields (ID, Data, Complexity, Deadline, Timeout and
+// TraceID) restored. In must be the input type of the pool that wrote the log.
+func PendingTasks[In, Out any](w *WAL) ([]TypedTask[In, Out], error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tasks := make([]TypedTask[In, Out], 0, len(w.pending))
+	for _, id := range w.pendingIDs() {
+		var t walTask[In]
+		if err := json.Unmarshal(w.pending[id], &t); err != nil {
+			return nil, fmt.Errorf("workerpool: decoding task %d: %w", id, err)
+		}
+		tasks = append(tasks, TypedTask[In, Out]{
+			ID:         t.ID,
+			Data:       t.Data,
+			Complexity: t.Complexity,
+			Deadline:   t.Deadline,
+			Timeout:    t.Timeout,
+			TraceID:    t.TraceID,
+		})
+	}
+	return tasks, nil
+}
+
+// walSubmit records that the pool accepted task.
+func walSubmit[In, Out any](w *WAL, task TypedTask[In, Out]) error {
+	data, err := json.Marshal(walTask[In]{
+		ID:         task.ID,
+		Data:       task.Data,
+		Complexity: task.Complexity,
+		Deadline:   task.Deadline,
+		Timeout:    task.Timeout,
+		TraceID:    task.TraceID,
+	})
+	if err != nil {
+		return err
+	}
+	return w.append(walRecord{Op: "submit", ID: task.ID, Task: data})
+}
+
+// complete records that the task with the given ID is finished.
+func (w *WAL) complete(id int) error {
+	return w.append(walRecord{Op: "complete", ID: id})
+}
+
+// append writes record, syncs it as the policy requires and compacts the log
+// once enough records are obsolete.
+func (w *WAL) append(record walRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if _, err := w.file.Write(line); err != nil {
+		return err
+	}
+	w.apply(record) // The record was built by the pool, so its operation is known.
+	w.dirty = true
+	if w.policy == SyncAlways {
+		if err := w.sync(); err != nil {
+			return err
+		}
+	}
+
+	if w.dead >= compactThreshold && w.dead > len(w.pending) {
+		return w.compact()
+	}
+	return nil
+}
+
+// Compact rewrites the log so that it only holds the pending tasks. The new log
+// is written to a temporary file and renamed over the old one, so a crash
+// during compaction leaves one of the two complete logs behind.
+func (w *WAL) Compact() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.compact()
+}
+
+// compact implements Compact. The caller must hold w.mu.
+func (w *WAL) compact() error {
+	tmpPath := w.path + ".tmp"
+	tmp, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath) // No-op once the rename succeeded.
+
+	out := bufio.NewWriter(tmp)
+	enc := json.NewEncoder(out)
+	// The next ID is kept, so IDs of completed tasks are never handed out again.
+	err = enc.Encode(walRecord{Op: "next", ID: w.nextID})
+	for _, id := range w.pendingIDs() {
+		if err != nil {
+			break
+		}
+		err = enc.Encode(walRecord{Op: "submit", ID: id, Task: w.pending[id]})
+	}
+	if err == nil {
+		err = out.Flush()
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, w.path); err != nil {
+		return err
+	}
+	syncDir(filepath.Dir(w.path))
+
+	file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	w.file.Close()
+	w.file = file
+	w.dead = 0
+	w.dirty = false
+	return nil
+}
+
+// pendingIDs returns the IDs of the pending tasks in increasing order.
+// The caller must hold w.mu.
+func (w *WAL) pendingIDs() []int {
+	ids := make([]int, 0, len(w.pending))
+	for id := range w.pending {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// sync fsyncs the file if records were written since the last fsync.
+// The caller must hold w.mu.
+func (w *WAL) sync() error {
+	if !w.dirty {
+		return nil
+	}
+	if err := w.file.Sync(); err != nil {
+		return err
+	}
+	w.dirty = false
+	return nil
+}
+
+// syncPeriodically fsyncs the file every interval until Close is called.
+func (w *WAL) syncPeriodically(interval time.Duration) {
+	defer close(w.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			w.sync() // A failure resurfaces at the next sync or at Close.
+			w.mu.Unlock()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Close syncs the log, unless the policy is SyncNever, and closes it.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return os.ErrClosed
+	}
+	w.closed = true
+	w.mu.Unlock()
+
+	// Stop the periodic sync before closing the file it uses.
+	if w.stop != nil {
+		close(w.stop)
+		<-w.stopped
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var err error
+	if w.policy != SyncNever {
+		err = w.sync()
+	}
+	if closeErr := w.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// syncDir fsyncs a directory so that a rename in it is durable. Errors are
+// ignored: not every platform supports syncing directories.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
@@ -0,0 +1,115 @@
+package exercise02workerpool_test
+
+import (
+	"bytes"         // Used to count the lines of the log.
+	"context"       // Used to interrupt the first run.
+	"os"            // Used to tamper with the log file.
+	"path/filepath" // Used to build the path of the log.
+	"strings"       // Used to check error messages.
+	"testing"       // The testing package is required for tests.
+	"time"          // Used for task complexities and timeouts.
+
+	exercise02workerpool "github.com/Daniel-Q-Reis/GoroutinesFromBeginningToAdvanced/Advanced/Exercise02_WorkerPool"
+)
+
+// TestWALReplay interrupts a run half-way and checks that the log returns
+// exactly the unfinished tasks, survives a torn last line, and compacts.
+func TestWALReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.wal")
+	wal, err := exercise02workerpool.OpenWAL(path, exercise02workerpool.SyncAlways)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tasks 5 and above never finish until the run is cancelled.
+	proc := exercise02workerpool.ProcessorFunc(func(ctx context.Context, task exercise02workerpool.Task) (any, error) {
+		if task.ID >= 5 {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		return task.Data * 2, nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := exercise02workerpool.NewPool(10, proc, exercise02workerpool.WithWAL(wal))
+	pool.Start(ctx)
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			pool.TaskChan <- exercise02workerpool.Task{ID: i, Data: 100 + i, Complexity: time.Duration(i)}
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		select {
+		case <-pool.ResultChan:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the first five tasks did not finish")
+		}
+	}
+	for pool.Stats().Submitted < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	collect(t, pool.ResultChan)
+	if err := wal.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash in the middle of a write leaves a torn last line behind.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"op":"compl`)
+	file.Close()
+
+	wal, err = exercise02workerpool.OpenWAL(path, exercise02workerpool.SyncNever)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	pending, err := exercise02workerpool.PendingTasks[int, any](wal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 5 {
+		t.Fatalf("got %d pending tasks, want 5", len(pending))
+	}
+	for i, task := range pending {
+		if id := 5 + i; task.ID != id || task.Data != 100+id || task.Complexity != time.Duration(id) {
+			t.Errorf("pending task %d is %+v, want ID %d with its input restored", i, task, id)
+		}
+	}
+	if next := wal.NextID(); next != 10 {
+		t.Errorf("NextID is %d, want 10", next)
+	}
+
+	if err := wal.Compact(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := bytes.Count(data, []byte("\n")); lines != 6 {
+		t.Errorf("the compacted log has %d lines, want 6 (next ID and five tasks)", lines)
+	}
+	if wal.Len() != 5 || wal.NextID() != 10 {
+		t.Errorf("after compaction got %d pending tasks and NextID %d, want 5 and 10", wal.Len(), wal.NextID())
+	}
+}
+
+// TestWALMalformed checks that a corrupt record that is not the last line is
+// reported with its line number.
+func TestWALMalformed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.wal")
+	content := `{"op":"submit","id":1,"task":{"id":1,"data":3}}` + "\n" + "garbage\n" + `{"op":"complete","id":1}` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := exercise02workerpool.OpenWAL(path, exercise02workerpool.SyncAlways)
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("OpenWAL returned %v, want an error about line 2", err)
+	}
+}
@@ -1,51 +1,272 @@
 package exercise02workerpool
 
-import "time"
+import (
+	"context"     // Package for cancellation signals propagated to the worker.
+	"errors"      // Package for defining ErrTaskTimeout.
+	"fmt"         // Package for wrapping ErrTaskTimeout with the limit that was exceeded.
+	"log/slog"    // Package for the structured events logged for every task.
+	"sync"        // Package for synchronization primitives, used to close the quit channel once.
+	"sync/atomic" // Package for lock-free counters read by the pool while the worker runs.
+	"time"        // Package for time-related functions, used to measure busy time and latency.
+)
 
-// Worker represents a single processing unit in the worker pool.
+// ErrTaskTimeout is wrapped by Task.Err when a task did not finish before its
+// deadline and was abandoned by the worker. Test for it with errors.Is.
+var ErrTaskTimeout = errors.New("workerpool: task timed out")
+
+// TypedWorker represents a single processing unit in the worker pool.
 // Its responsibility is to take tasks from an input channel, process them,
 // and then send the results to an output channel.
-type Worker struct {
-	ID            int         // Unique identifier for the worker, useful for logging and debugging.
-	TaskChannel   <-chan Task // A receive-only channel from which the worker receives tasks.
-	ResultChannel chan<- Task // A send-only channel to which the worker sends processed tasks (results).
+type TypedWorker[In, Out any] struct {
+	ID            int                       // Unique identifier for the worker, useful for logging and debugging.
+	Processor     TypedProcessor[In, Out]   // The processing step applied to every task this worker receives.
+	TaskChannel   <-chan TypedTask[In, Out] // A receive-only channel from which the worker receives tasks.
+	ResultChannel chan<- TypedTask[In, Out] // A send-only channel to which the worker sends processed tasks (results).
+	Timeout       time.Duration             // Default timeout for tasks that set neither Deadline nor Timeout. Zero means none.
+	Logger        *slog.Logger              // Receives an event for every task attempt. Nil logs nothing.
+	Breaker       *CircuitBreaker           // Fails attempts fast while open. Nil processes every attempt.
+
+	quit     chan struct{} // Closed by Stop to ask the worker to exit before its next task.
+	stopOnce sync.Once     // Guards quit so that Stop can be called more than once.
+
+	// Counters read concurrently by the pool (e.g. by the Autoscaler).
+	busyNanos    atomic.Int64 // Total time spent inside the Processor for finished tasks.
+	busySince    atomic.Int64 // UnixNano when the current task started processing; 0 while idle.
+	latencyNanos atomic.Int64 // Total time from taking a task to delivering its result.
+	delivered    atomic.Int64 // Number of results delivered to ResultChannel.
 }
 
-// NewWorker creates and returns a new instance of a Worker.
-// It initializes the worker with an ID and the channels it will use for communication.
-func NewWorker(id int, taskChan <-chan Task, resultChannel chan<- Task) *Worker {
-	return &Worker{
+// Worker is the worker type of the original prime-checking pool.
+type Worker = TypedWorker[int, any]
+
+// NewTypedWorker creates and returns a new instance of a TypedWorker.
+// It initializes the worker with an ID, the processor it applies to each task and
+// the channels it will use for communication. The processor must not be nil.
+func NewTypedWorker[In, Out any](id int, processor TypedProcessor[In, Out], taskChan <-chan TypedTask[In, Out], resultChannel chan<- TypedTask[In, Out]) *TypedWorker[In, Out] {
+	return &TypedWorker[In, Out]{
 		ID:            id,            // Assigns the given ID to the worker.
+		Processor:     processor,     // Assigns the processing step.
 		TaskChannel:   taskChan,      // Assigns the task input channel.
 		ResultChannel: resultChannel, // Assigns the result output channel.
+		quit:          make(chan struct{}),
+	}
+}
+
+// NewWorker creates and returns a new Worker for Task values.
+// A nil processor selects PrimeProcessor.
+func NewWorker(id int, processor Processor, taskChan <-chan Task, resultChannel chan<- Task) *Worker {
+	if processor == nil {
+		processor = PrimeProcessor{} // Keeps the original prime-checking workload as the default.
 	}
+	return NewTypedWorker(id, processor, taskChan, resultChannel)
 }
 
 // Start begins the worker's main processing loop.
-// This method is designed to be run in its own goroutine.
-func (w *Worker) Start() {
-	// The 'for range' loop over a channel will continuously receive values
-	// until the channel is closed. Once the channel is closed and all
-	// values have been received, the loop will terminate.
-	for task := range w.TaskChannel {
-		// Simulate Processing time based on the task's defined complexity.
-		// This uses time.Sleep to block the goroutine for a specified duration,
-		// mimicking actual work being done that consumes time.
-		time.Sleep(task.Complexity)
-
-		// Perform the CPU-intensive calculation for the task.
-		// The 'isPrime' function is called with the task's data.
-		// The result of this computation is assigned to the 'Result' field of the task.
-		// Since 'task' is a value received from a channel, modifying it here is safe
-		// as it's a local copy, not shared with other goroutines concurrently.
-		task.Result = isPrime(task.Data)
-		// Set any error to nil, assuming successful processing for this example.
-		task.Err = nil
-
-		// Send the processed task (now containing the result) back to the ResultChannel.
+// This method is designed to be run in its own goroutine. It returns when the
+// TaskChannel is closed and drained, as soon as ctx is cancelled, or before
+// taking a new task once Stop has been called.
+func (w *TypedWorker[In, Out]) Start(ctx context.Context) {
+	// Every event this worker logs carries its ID.
+	logger := loggerOrDiscard(w.Logger).With("worker", w.ID)
+	logger.DebugContext(ctx, "worker started")
+	defer logger.DebugContext(ctx, "worker stopped")
+
+	for {
+		// A select with several ready cases picks one at random, so check the
+		// context and the quit signal explicitly to avoid taking new work after
+		// a cancellation or a call to Stop.
+		if ctx.Err() != nil || w.stopped() {
+			return
+		}
+
+		// The select waits for whichever happens first: a new task or a cancellation.
+		// Checking ctx.Done() here means an idle worker stops immediately when the
+		// run is aborted, instead of waiting for the producer to close TaskChannel.
+		var task TypedTask[In, Out]
+		var ok bool
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.quit:
+			// The worker was retired (e.g. the pool shrank). It only gets here
+			// between tasks, so the task it was working on has been delivered.
+			return
+		case task, ok = <-w.TaskChannel:
+			if !ok {
+				// The channel was closed by the Producer and all tasks have been received.
+				// At this point, the worker goroutine will finish its execution.
+				return
+			}
+		}
+
+		task.Attempts++ // Counts this attempt, including retries scheduled by the pool.
+
+		// Record that the worker is busy from now on, so utilisation can be
+		// observed while a long task is still running.
+		takenAt := time.Now()
+		task.takenAt = takenAt
+		task.workerID = w.ID
+		w.busySince.Store(takenAt.UnixNano())
+
+		// Hand the task to the Processor. The returned value becomes the task's
+		// Result and the returned error lands in task.Err. Since 'task' is a value
+		// received from a channel, modifying it here is safe as it's a local copy,
+		// not shared with other goroutines concurrently.
+		// Processors are expected to honour ctx: if the run is cancelled mid-task,
+		// they abandon the work and report ctx.Err().
+		task.Result, task.Err = w.process(ctx, task, takenAt)
+		if task.Err != nil {
+			task.Errors = append(task.Errors, task.Err) // Keeps the history across retries.
+		}
+
+		// Only the processing itself counts as busy time: a worker waiting for
+		// room in ResultChannel is held up by the consumer, not by its own work.
+		task.processedAt = time.Now()
+		w.busyNanos.Add(int64(task.processedAt.Sub(takenAt)))
+		w.busySince.Store(0)
+		w.logAttempt(ctx, logger, task)
+
+		// Send the processed (or abandoned) task back to the ResultChannel.
 		// This sends the task to the consumer or further processing stages.
-		w.ResultChannel <- task
+		if !w.deliver(ctx, task) {
+			return
+		}
+		w.latencyNanos.Add(int64(time.Since(takenAt)))
+		w.delivered.Add(1)
+	}
+}
+
+// logAttempt logs the outcome of the attempt that has just been processed.
+func (w *TypedWorker[In, Out]) logAttempt(ctx context.Context, logger *slog.Logger, task TypedTask[In, Out]) {
+	attrs := []slog.Attr{
+		slog.Int("task", task.ID),
+		slog.Int("attempt", task.Attempts),
+		slog.Duration("duration", task.processedAt.Sub(task.takenAt)),
+	}
+	if task.Err == nil {
+		logger.LogAttrs(ctx, slog.LevelDebug, "task processed", attrs...)
+		return
+	}
+
+	attrs = append(attrs, slog.Any("error", task.Err))
+	var panicErr *PanicError
+	if errors.As(task.Err, &panicErr) {
+		attrs = append(attrs, slog.String("stack", string(panicErr.Stack)))
+	}
+	logger.LogAttrs(ctx, errorLevel(task.Err), "task attempt failed", attrs...)
+}
+
+// process runs the Processor for task unless the circuit breaker rejects the
+// attempt, and reports the outcome to the breaker.
+func (w *TypedWorker[In, Out]) process(ctx context.Context, task TypedTask[In, Out], takenAt time.Time) (Out, error) {
+	if !w.Breaker.Allow() {
+		var zero Out
+		return zero, fmt.Errorf("%w: task %d rejected by worker %d", ErrCircuitOpen, task.ID, w.ID)
+	}
+	result, err := w.processWithDeadline(ctx, task, takenAt)
+	w.Breaker.Record(err)
+	return result, err
+}
+
+// processWithDeadline runs the Processor for task, enforcing the task's deadline
+// if it has one. A panic in the Processor is recovered and returned as a
+// *PanicError, so the worker keeps serving the next tasks.
+//
+// Without a deadline the Processor runs in the worker's goroutine. With one, it
+// runs in a separate goroutine so that the worker can abandon it when the deadline
+// passes, even if the Processor ignores its context; the abandoned call keeps
+// running in the background until it returns, and its outcome is discarded.
+func (w *TypedWorker[In, Out]) processWithDeadline(ctx context.Context, task TypedTask[In, Out], takenAt time.Time) (Out, error) {
+	deadline, ok := w.deadline(task, takenAt)
+	if !ok {
+		return safeProcess(ctx, w.Processor, task)
+	}
+
+	var zero Out
+	if !takenAt.Before(deadline) {
+		// The deadline passed while the task was waiting: do not start the work at all.
+		return zero, w.timeoutError(task, takenAt)
+	}
+
+	taskCtx, cancel := context.WithDeadlineCause(ctx, deadline, ErrTaskTimeout)
+	defer cancel()
+
+	type outcome struct {
+		result Out
+		err    error
+	}
+	done := make(chan outcome, 1) // Buffered, so an abandoned call never blocks on its send.
+	go func() {
+		result, err := safeProcess(taskCtx, w.Processor, task)
+		done <- outcome{result, err}
+	}()
+
+	select {
+	case o := <-done:
+		// A Processor that honours its context returns an error as the deadline
+		// passes; report it as the timeout it really is.
+		if o.err != nil && ctx.Err() == nil && errors.Is(context.Cause(taskCtx), ErrTaskTimeout) {
+			return o.result, w.timeoutError(task, takenAt)
+		}
+		return o.result, o.err
+	case <-taskCtx.Done():
+		if err := ctx.Err(); err != nil {
+			return zero, err // The whole run was cancelled, not just this task.
+		}
+		return zero, w.timeoutError(task, takenAt)
+	}
+}
+
+// deadline returns the deadline that applies to task, if any. A task's own
+// Deadline wins over its Timeout, which wins over the worker's default Timeout;
+// timeouts are counted from when the worker took the task.
+func (w *TypedWorker[In, Out]) deadline(task TypedTask[In, Out], takenAt time.Time) (time.Time, bool) {
+	switch {
+	case !task.Deadline.IsZero():
+		return task.Deadline, true
+	case task.Timeout > 0:
+		return takenAt.Add(task.Timeout), true
+	case w.Timeout > 0:
+		return takenAt.Add(w.Timeout), true
+	default:
+		return time.Time{}, false
 	}
-	// The loop exits when w.TaskChannel is closed by the Producer.
-	// At this point, the worker goroutine will finish its execution.
+}
+
+// timeoutError builds the error reported for a task that missed its deadline.
+func (w *TypedWorker[In, Out]) timeoutError(task TypedTask[In, Out], takenAt time.Time) error {
+	return fmt.Errorf("%w: task %d abandoned by worker %d after %v",
+		ErrTaskTimeout, task.ID, w.ID, time.Since(takenAt).Round(time.Millisecond))
+}
+
+// Stop asks the worker to exit. A worker that is processing a task finishes and
+// delivers it first; Stop does not wait for that to happen.
+func (w *TypedWorker[In, Out]) Stop() {
+	w.stopOnce.Do(func() { close(w.quit) })
+}
+
+// BusyTime returns the total time this worker has spent processing tasks,
+// including the elapsed part of the task it is working on right now.
+func (w *TypedWorker[In, Out]) BusyTime() time.Duration {
+	busy := time.Duration(w.busyNanos.Load())
+	if since := w.busySince.Load(); since != 0 {
+		busy += time.Since(time.Unix(0, since))
+	}
+	return busy
+}
+
+// stopped reports whether Stop has been called.
+func (w *TypedWorker[In, Out]) stopped() bool {
+	select {
+	case <-w.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// deliver sends task to the ResultChannel and reports whether it was sent.
+// After a cancellation it never blocks; see send.
+func (w *TypedWorker[In, Out]) deliver(ctx context.Context, task TypedTask[In, Out]) bool {
+	return send(ctx, w.ResultChannel, task)
 }